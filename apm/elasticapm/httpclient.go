@@ -0,0 +1,46 @@
+package elasticapm
+
+import (
+	"net/http"
+	"time"
+
+	"go.elastic.co/apm"
+	"go.elastic.co/apm/module/apmhttp"
+
+	"github.com/PCS-Indonesia/pakakeh/httpclient"
+)
+
+// HTTPClientTrace returns a httpclient.TraceCallback that reports every attempt made by
+// pakakeh httpclient as an external span of the transaction found in the request context.
+// The connection timings are added as span labels. Use it with httpclient.WithHTTPTrace.
+func HTTPClientTrace() httpclient.TraceCallback {
+	return func(request *http.Request, timings httpclient.Timings) {
+		ctx := request.Context()
+		tx := apm.TransactionFromContext(ctx)
+		if tx == nil {
+			return
+		}
+
+		opts := apm.SpanOptions{Start: time.Now().Add(-timings.Total)}
+		if parent := apm.SpanFromContext(ctx); parent != nil {
+			opts.Parent = parent.TraceContext()
+		}
+
+		span := tx.StartSpanOptions(apmhttp.ClientRequestName(request), "external.http", opts)
+		defer span.End()
+
+		span.Duration = timings.Total
+		span.Context.SetHTTPRequest(request)
+		span.Context.SetLabel("attempt", timings.Attempt)
+		span.Context.SetLabel("dns_ms", timings.DNSLookup.Milliseconds())
+		span.Context.SetLabel("connect_ms", timings.TCPConnect.Milliseconds())
+		span.Context.SetLabel("tls_ms", timings.TLSHandshake.Milliseconds())
+		span.Context.SetLabel("ttfb_ms", timings.TimeToFirstByte.Milliseconds())
+		span.Context.SetLabel("transfer_ms", timings.BodyTransfer.Milliseconds())
+		span.Context.SetLabel("conn_reused", timings.ConnReused)
+
+		if timings.Err != nil {
+			span.Outcome = "failure"
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package opentelemetry
+
+import (
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.25.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/PCS-Indonesia/pakakeh/httpclient"
+)
+
+const httpClientTracerName = "github.com/PCS-Indonesia/pakakeh/httpclient"
+
+// HTTPClientTrace returns a httpclient.TraceCallback that reports every attempt made by
+// pakakeh httpclient as a client span of the global tracer provider, child of the span found
+// in the request context. The connection timings are added as span attributes. Use it with
+// httpclient.WithHTTPTrace after InitTracer.
+func HTTPClientTrace() httpclient.TraceCallback {
+	return func(request *http.Request, timings httpclient.Timings) {
+		end := time.Now()
+		tracer := otel.GetTracerProvider().Tracer(httpClientTracerName)
+
+		_, span := tracer.Start(request.Context(), "HTTP "+request.Method,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithTimestamp(end.Add(-timings.Total)),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(request.Method),
+				semconv.URLFull(request.URL.Redacted()),
+				semconv.ServerAddress(request.URL.Hostname()),
+				attribute.Int("http.client.attempt", timings.Attempt),
+				attribute.Int64("http.client.dns_ms", timings.DNSLookup.Milliseconds()),
+				attribute.Int64("http.client.connect_ms", timings.TCPConnect.Milliseconds()),
+				attribute.Int64("http.client.tls_ms", timings.TLSHandshake.Milliseconds()),
+				attribute.Int64("http.client.ttfb_ms", timings.TimeToFirstByte.Milliseconds()),
+				attribute.Int64("http.client.transfer_ms", timings.BodyTransfer.Milliseconds()),
+				attribute.Bool("http.client.conn_reused", timings.ConnReused),
+			),
+		)
+
+		if timings.Err != nil {
+			span.RecordError(timings.Err)
+			span.SetStatus(codes.Error, timings.Err.Error())
+		}
+		span.End(trace.WithTimestamp(end))
+	}
+}
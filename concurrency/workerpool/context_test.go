@@ -0,0 +1,90 @@
+package workerpool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hungHandler blocks every job named "hung" until its context is done
+func hungHandler() ContextJobHandlerFunc[string] {
+	return func() ContextJobFunc[string] {
+		return func(ctx context.Context, job string) error {
+			if job != "hung" {
+				return nil
+			}
+			<-ctx.Done()
+			return ctx.Err()
+		}
+	}
+}
+
+func TestJobTimeout(t *testing.T) {
+	done := make(chan struct{})
+	p := NewWithContext(done, hungHandler(), func(c *Config) error {
+		c.WorkerNum = 2
+		c.Errors = true
+		c.JobTimeout = time.Hour
+		return nil
+	})
+	p.Start()
+
+	outcomes := make(chan error, 1)
+	require.NoError(t, p.Submit(context.Background(), "hung", WithTimeout(10*time.Millisecond),
+		WithDone(func(err error) { outcomes <- err })))
+	require.NoError(t, p.Submit(context.Background(), "inquiry"))
+
+	var jobErr *JobError[string]
+	require.ErrorAs(t, <-p.Errors, &jobErr)
+	assert.Equal(t, "hung", jobErr.Job)
+	assert.ErrorIs(t, jobErr, ErrJobTimeout)
+	assert.ErrorIs(t, jobErr, context.DeadlineExceeded)
+	assert.Contains(t, jobErr.Error(), "timed out after 10ms")
+	assert.ErrorIs(t, <-outcomes, ErrJobTimeout)
+
+	close(done)
+	waitClosed(t, p)
+}
+
+func TestStopCancelsRunningJobs(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		stop func(p *Pool[string])
+	}{
+		{name: "stop", stop: func(p *Pool[string]) { p.Stop() }},
+		{name: "shutdown deadline", stop: func(p *Pool[string]) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			_, err := p.Shutdown(ctx)
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			done := make(chan struct{})
+			defer close(done)
+
+			p := NewWithContext(done, hungHandler(), func(c *Config) error {
+				c.WorkerNum = 1
+				c.MaxAttempts = 3
+				return nil
+			})
+			p.Start()
+
+			outcomes := make(chan error, 1)
+			require.NoError(t, p.Submit(context.Background(), "hung", WithDone(func(err error) { outcomes <- err })))
+			require.Eventually(t, func() bool { return p.Stats().BusyWorkers == 1 }, time.Second, time.Millisecond)
+
+			tc.stop(p)
+			p.Wait()
+			waitClosed(t, p)
+
+			// cancelled by the pool, so neither retried nor reported as a timeout
+			err := <-outcomes
+			assert.ErrorIs(t, err, ErrJobAbandoned)
+			assert.NotErrorIs(t, err, ErrJobTimeout)
+		})
+	}
+}
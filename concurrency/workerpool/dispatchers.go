@@ -13,39 +13,37 @@ type (
 )
 
 // NewDispatcher creates a dispatcher.
-func NewDispatcher(done <-chan struct{}, wgPool *sync.WaitGroup, numWorkers int, jobQueue <-chan Job,
-	jobFunc JobFunc, errors chan error) *Dispatcher {
-	wp := make(chan chan Job, numWorkers)
-	return &Dispatcher{
+func NewDispatcher[T any](done <-chan struct{}, wgPool *sync.WaitGroup, numWorkers int, executor *executor[T],
+	jobFunc ContextJobFunc[T]) *Dispatcher[T] {
+	wp := make(chan chan task[T], numWorkers)
+	return &Dispatcher[T]{
 		workerPool: wp,
 		numWorkers: numWorkers,
-		jobQueue:   jobQueue,
+		jobQueue:   executor.queue,
 		jobHandler: jobFunc,
 		wg:         &sync.WaitGroup{},
-		wgJob:      &sync.WaitGroup{},
 		wgPool:     wgPool,
-		errors:     errors,
+		executor:   executor,
 		done:       done,
 		doneWorker: make(chan struct{}, numWorkers),
-		jobPool:    make(chan struct{}, numWorkers),
 		mu:         &sync.Mutex{},
 	}
 }
 
 // Dispatch creates the workers pool and dispatches available jobs.
-func (d *Dispatcher) Dispatch() {
+func (d *Dispatcher[T]) Dispatch() {
 	// starting the workers
 	d.wg.Add(d.numWorkers)
 	// starting all workers in the dispatcher
 	for i := 0; i < d.numWorkers; i++ {
-		worker := NewWorker(d.doneWorker, d.workerPool, d.wg, d.jobPool, d.errors)
+		worker := NewWorker(d.doneWorker, d.workerPool, d.wg, d.executor)
 		worker.Start(d.jobHandler)
 	}
 
 	go d.startDispatch()
 }
 
-func (d *Dispatcher) Closed() bool {
+func (d *Dispatcher[T]) Closed() bool {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
@@ -53,7 +51,7 @@ func (d *Dispatcher) Closed() bool {
 }
 
 // StopWorker signals worker to stop. Default worker is 1
-func (d *Dispatcher) StopWorker(numWorkers ...int) {
+func (d *Dispatcher[T]) StopWorker(numWorkers ...int) {
 	n := Min(1, d.numWorkers)
 	if len(numWorkers) > 0 && numWorkers[0] > 1 {
 		n = Min(numWorkers[0], d.numWorkers)
@@ -72,46 +70,67 @@ func (d *Dispatcher) StopWorker(numWorkers ...int) {
 	}
 }
 
-func (d *Dispatcher) startDispatch() {
+func (d *Dispatcher[T]) startDispatch() {
+	defer d.wgPool.Done()
+
+	var bucket chan task[T]
 	for {
-		select {
-		case job, open := <-d.jobQueue:
-			if !open {
-				d.jobQueue = nil
-				d.closeWorkerDoneCh()
-				d.wgPool.Done()
-				break
+		// take an idle worker first, so jobs keep waiting in the queue
+		if bucket == nil {
+			select {
+			case bucket = <-d.workerPool:
+			case <-d.done:
+				d.stop()
+				return
 			}
+		}
 
-			d.wgJob.Add(1)
-
-			go func(j Job) {
-				d.jobPool <- struct{}{} // will block until worker is available
-				w := <-d.workerPool
-				w <- j // dispatch job to worker's job channel
-				d.wgJob.Done()
-			}(job)
-		case <-d.done:
-			d.done = nil
+		if !d.jobQueue.ready(d.done) {
+			// the queue is closed and drained, or the dispatcher was told to stop
+			d.stop()
+			return
+		}
 
-			d.closeWorkerDoneCh()
-			close(d.workerPool)
-			close(d.jobPool)
+		// the rate limit token is only taken once a job is queued, so an idle dispatcher
+		// holds none, and the job keeps waiting in the queue meanwhile
+		if !d.executor.acquireRate(d.done) {
+			d.stop()
+			return
+		}
 
-			d.mu.Lock()
-			if !d.closed {
-				d.closed = true
-			}
-			d.mu.Unlock()
+		job, ok := d.jobQueue.tryPop()
+		if !ok {
+			// another dispatcher took the job first
+			d.executor.releaseRate()
+			continue
+		}
 
-			return
+		if delay := d.executor.admit(job); delay > 0 {
+			// its key is limited, the worker and the pool token stay free for the other jobs
+			d.executor.releaseRate()
+			d.jobQueue.schedule(job, job.options.priority, delay)
+			d.jobQueue.done()
+			continue
 		}
+
+		bucket <- job // dispatch job to worker's job channel
+		bucket = nil
+	}
+}
+
+func (d *Dispatcher[T]) stop() {
+	d.closeWorkerDoneCh()
+
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
 	}
+	d.mu.Unlock()
 }
 
-func (d *Dispatcher) closeWorkerDoneCh() {
+// closeWorkerDoneCh stops the workers and waits for their running jobs
+func (d *Dispatcher[T]) closeWorkerDoneCh() {
 	d.once.Do(func() {
-		d.wgJob.Wait()
 		close(d.doneWorker)
 		d.wg.Wait()
 	})
@@ -1,26 +1,61 @@
 package workerpool
 
-import "sync"
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/PCS-Indonesia/pakakeh/httpclient"
+)
 
 type (
+	// Job is the untyped job of the previous API, Pool[Job] and JobFunc[Job] keep its behavior.
+	//
+	// Deprecated: use a Pool of your own job type, so handlers need no type assertion.
 	Job struct {
 		Data interface{}
 	}
 
-	// Pool represents a pool with dispatcher.
-	Pool struct {
-		JobQueue       chan Job // JobQueue channel for incoming job request
-		JobHandlerFunc JobHandlerFunc
+	// Pool represents a pool with dispatcher, processing jobs of type T.
+	Pool[T any] struct {
+		// JobQueue channel for incoming job request.
+		//
+		// Deprecated: use Submit or TrySubmit. Jobs sent before Start wait in a buffer of
+		// JobQueueBufferSize. Sending blocks while the queue is full and panics once the
+		// pool is closed, since closing the pool closes JobQueue.
+		JobQueue       chan T
+		JobHandlerFunc JobHandlerFunc[T]
+		contextHandler ContextJobHandlerFunc[T] // set by NewWithContext instead of JobHandlerFunc
 		config         Config
 		poolNum        int // current number of dispatcher pool
 		Errors         chan error
 		done           <-chan struct{} // done channel signals the pool to stop,
-		doneDispatcher chan struct{}
+		dispatchers    []chan struct{} // retire channel of every running dispatcher, oldest first
+		draining       chan struct{}   // closed when the pool stops taking jobs
+		stopping       bool
+		stopOnce       sync.Once
+		stopped        chan struct{} // closed once the pool is closed and its dispatchers are gone
+		abandoned      int           // jobs dropped because the pool was closed before Start
+		quietSince     time.Time     // since when the supervisor saw a quiet pool
+		queue          *jobQueue[task[T]]
+		executor       *executor[T]
+		cancel         context.CancelFunc // cancels the context of the running jobs
+		forwarded      chan struct{}      // closed when everything sent to JobQueue is queued
+		forwardOnce    sync.Once          // runs forward from Start, or drops JobQueue when never started
 		wg             *sync.WaitGroup
 		mu             *sync.Mutex
 		closed         bool
 	}
 
+	// task is a queued job with what the pool needs to process it
+	task[T any] struct {
+		job     T
+		options jobOptions
+		attempt int               // attempts made so far
+		run     ContextJobFunc[T] // replaces the dispatcher JobFunc, used by SubmitWithResult
+		result  func(err error)   // called once with the final outcome
+	}
+
 	// Config
 	Config struct {
 		InitDispatcherNum  int
@@ -28,35 +63,73 @@ type (
 		WorkerNum          int
 		JobQueueBufferSize int
 		Errors             bool // if true, pool will send errors to Errors channel
+
+		// PriorityAging lets queued jobs gain one priority level for every PriorityAging
+		// they wait, so that low priority jobs are not starved by a steady flow of higher
+		// priority ones. 0 disables aging.
+		PriorityAging time.Duration
+
+		// JobTimeout bounds every attempt of a job processed by a ContextJobFunc, 0 means no
+		// limit. WithTimeout overrides it for a single job. A job that fails once its context
+		// timed out is reported with an error wrapping ErrJobTimeout.
+		JobTimeout time.Duration
+
+		// RateLimit bounds how many jobs per second the pool starts, on top of WorkerNum
+		// bounding how many run at once. Dispatchers hold back until a token is available.
+		RateLimit RateLimit
+		// KeyRateLimits bounds the jobs submitted WithRateKey, per key. A job whose key has
+		// no token yet waits in a timer, so it does not hold back the jobs of other keys.
+		KeyRateLimits map[string]RateLimit
+
+		// MaxAttempts is the number of times a failing job is run, 0 and 1 disable retries
+		MaxAttempts int
+		// Backoff is the wait before a job is run again, default is exponential from
+		// 100ms up to 10s. The job waits in a timer, not in a worker.
+		Backoff httpclient.Backoff
+		// Retryable decides whether a failed job is run again, default is every error
+		// except the ones wrapped with Permanent
+		Retryable func(err error) bool
+		// PanicHandler receives the panics recovered from the jobs, e.g. to forward them to
+		// logger or APM. Default logs the panic with its stack. The panic is then reported
+		// like any other job error.
+		PanicHandler func(err *PanicError)
+
+		// ScaleInterval is how often the supervisor checks the load. It adds a dispatcher,
+		// up to MaxDispatcherNum, when jobs are queued and the worker utilization is at least
+		// ScaleUpUtilization. It retires one, down to InitDispatcherNum, when the queue is
+		// empty and the utilization stayed at most ScaleDownUtilization for ScaleCoolDown.
+		ScaleInterval        time.Duration
+		ScaleCoolDown        time.Duration
+		ScaleUpUtilization   float64
+		ScaleDownUtilization float64
+		// OnScale is called after every scaling decision of the supervisor
+		OnScale func(event ScaleEvent)
 	}
 
-	Worker struct {
-		pool    chan<- chan Job
-		jobPool <-chan struct{}
-		bucket  chan Job
-		done    <-chan struct{}
-		errors  chan error
-		wg      *sync.WaitGroup
-		closed  bool
-		mu      *sync.Mutex
+	Worker[T any] struct {
+		pool     chan<- chan task[T]
+		bucket   chan task[T]
+		done     <-chan struct{}
+		executor *executor[T]
+		wg       *sync.WaitGroup
+		closed   bool
+		mu       *sync.Mutex
 	}
 
 	// Dispatcher handling dispatch the job to the worker.
-	Dispatcher struct {
+	Dispatcher[T any] struct {
 		// a pool of workers bucket
-		workerPool chan chan Job
-		jobPool    chan struct{}
-		jobQueue   <-chan Job
+		workerPool chan chan task[T]
+		jobQueue   *jobQueue[task[T]]
 		doneWorker chan struct{}
 		once       sync.Once
-		errors     chan error
+		executor   *executor[T]
 		done       <-chan struct{}
 		wg         *sync.WaitGroup
-		wgJob      *sync.WaitGroup
 		wgPool     *sync.WaitGroup
 		closed     bool
 		mu         *sync.Mutex
 		numWorkers int
-		jobHandler JobFunc
+		jobHandler ContextJobFunc[T]
 	}
 )
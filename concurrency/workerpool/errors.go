@@ -0,0 +1,14 @@
+package workerpool
+
+import "errors"
+
+var (
+	// ErrQueueFull is returned by TrySubmit when the job queue has no free slot
+	ErrQueueFull = errors.New("workerpool: job queue is full")
+	// ErrPoolClosed is returned when a job is submitted after the pool was shut down
+	ErrPoolClosed = errors.New("workerpool: pool is closed")
+	// ErrJobAbandoned is the error of a job dropped by Shutdown or Stop before it could run
+	ErrJobAbandoned = errors.New("workerpool: job abandoned at shutdown")
+	// ErrJobTimeout is wrapped in the error of a job that failed after its timeout
+	ErrJobTimeout = errors.New("workerpool: job timed out")
+)
@@ -1,23 +1,23 @@
 package workerpool_test
 
 import (
+	"context"
 	"fmt"
 	"sync"
-	"testing"
 
 	"github.com/PCS-Indonesia/pakakeh/concurrency/workerpool"
 )
 
-func ExamplePool(t *testing.T) {
+func ExamplePool() {
 	done := make(chan struct{})
 	mu := &sync.RWMutex{}
 	data := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13}
 	sum := 0
-	jobHandlerFunc := func() workerpool.JobFunc {
-		return func(j workerpool.Job) error {
+	jobHandlerFunc := func() workerpool.JobFunc[int] {
+		return func(n int) error {
 			mu.Lock()
 			defer mu.Unlock()
-			sum += j.Data.(int)
+			sum += n
 			return nil
 		}
 	}
@@ -34,23 +34,18 @@ func ExamplePool(t *testing.T) {
 	p.Start()
 
 	for i := range data {
-		p.JobQueue <- workerpool.Job{
-			Data: data[i],
+		if err := p.Submit(context.Background(), data[i]); err != nil {
+			fmt.Println(err)
 		}
 	}
 
-	close(done)
-
 	// wait for jobs to finish
-	for {
-		// time.Sleep(1 * time.Second)
-		if p.Closed() {
-			break
-		}
-	}
+	p.Wait()
+	close(done)
 
 	mu.RLock()
 	fmt.Println(sum)
 	mu.RUnlock()
 
+	// Output: 728
 }
@@ -0,0 +1,299 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
+	"github.com/PCS-Indonesia/pakakeh/httpclient"
+)
+
+// defaultRetryBackoff is used when retries are enabled without a Backoff
+var defaultRetryBackoff = httpclient.NewExponentialBackoff(100*time.Millisecond, 10*time.Second, 2, 0)
+
+// JobError is reported when a job failed for good, after its last attempt
+type JobError[T any] struct {
+	Job      T
+	Attempts int
+	Err      error
+}
+
+func (e *JobError[T]) Error() string {
+	if e.Attempts > 1 {
+		return fmt.Sprintf("job failed after %d attempts: %v", e.Attempts, e.Err)
+	}
+	return e.Err.Error()
+}
+
+func (e *JobError[T]) Unwrap() error {
+	return e.Err
+}
+
+// PanicError is the error of a job whose JobFunc panicked
+type PanicError struct {
+	Value interface{} // the value given to panic
+	Stack []byte      // the stack of the panicking goroutine
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("job panicked: %v", e.Value)
+}
+
+// Unwrap returns the panic value when it is an error
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not retryable for the default retry classifier
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// isRetryable is the default retry classifier, every error except the Permanent ones
+// and the panics, which are bugs rather than transient failures
+func isRetryable(err error) bool {
+	var permanent *permanentError
+	var panicked *PanicError
+	return !errors.As(err, &permanent) && !errors.As(err, &panicked)
+}
+
+// JobOption customizes a single job
+type JobOption func(*jobOptions)
+
+type jobOptions struct {
+	maxAttempts int
+	backoff     httpclient.Backoff
+	retryable   func(error) bool
+	priority    Priority
+	timeout     time.Duration
+	rateKey     string
+	done        func(error)
+}
+
+// WithRetry overrides the pool MaxAttempts and Backoff for the job. A nil backoff keeps
+// the pool Backoff.
+func WithRetry(maxAttempts int, backoff httpclient.Backoff) JobOption {
+	return func(o *jobOptions) {
+		o.maxAttempts = maxAttempts
+		o.backoff = backoff
+	}
+}
+
+// WithRetryable overrides the pool Retryable classifier for the job
+func WithRetryable(retryable func(error) bool) JobOption {
+	return func(o *jobOptions) {
+		o.retryable = retryable
+	}
+}
+
+// WithTimeout overrides the pool JobTimeout for the job, it bounds every attempt
+func WithTimeout(timeout time.Duration) JobOption {
+	return func(o *jobOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithDone calls fn once the job finished for good, with nil on success or the *JobError of
+// its last attempt. A job abandoned at shutdown gets an error wrapping ErrJobAbandoned.
+// When WithDone is given more than once, every fn is called in the order of the options.
+func WithDone(fn func(err error)) JobOption {
+	return func(o *jobOptions) {
+		previous := o.done
+		if previous == nil || fn == nil {
+			if fn != nil {
+				o.done = fn
+			}
+			return
+		}
+
+		o.done = func(err error) {
+			previous(err)
+			fn(err)
+		}
+	}
+}
+
+func newTask[T any](job T, opts []JobOption) task[T] {
+	t := task[T]{job: job}
+	for _, opt := range opts {
+		opt(&t.options)
+	}
+	t.result = t.options.done
+	return t
+}
+
+// executor runs the tasks handed to the workers of a pool and reports their outcome,
+// it is shared by every dispatcher of the pool
+type executor[T any] struct {
+	ctx    context.Context // cancelled when the pool stops without waiting for the jobs
+	queue  *jobQueue[task[T]]
+	config Config
+	errors chan error
+	busy   atomic.Int32 // workers running a job
+
+	limiter     *httpclient.RateLimiter            // nil without Config.RateLimit
+	keyLimiters map[string]*httpclient.RateLimiter // from Config.KeyRateLimits
+}
+
+func newExecutor[T any](ctx context.Context, queue *jobQueue[task[T]], config Config, errors chan error) *executor[T] {
+	e := &executor[T]{
+		ctx:         ctx,
+		queue:       queue,
+		config:      config,
+		errors:      errors,
+		limiter:     newRateLimiter(config.RateLimit),
+		keyLimiters: make(map[string]*httpclient.RateLimiter, len(config.KeyRateLimits)),
+	}
+
+	for key, limit := range config.KeyRateLimits {
+		if limiter := newRateLimiter(limit); limiter != nil {
+			e.keyLimiters[key] = limiter
+		}
+	}
+
+	return e
+}
+
+// acquireRate blocks until it took a token of the pool RateLimit. It returns false when
+// stop is closed or the pool stops without waiting for the jobs first.
+func (e *executor[T]) acquireRate(stop <-chan struct{}) bool {
+	if e.limiter == nil {
+		return true
+	}
+	return acquireToken(e.limiter, stop, e.ctx.Done())
+}
+
+// releaseRate gives back the pool token of a job that was not dispatched
+func (e *executor[T]) releaseRate() {
+	if e.limiter != nil {
+		e.limiter.Refund()
+	}
+}
+
+// admit takes the key rate limit token of t. When the key of t has no token yet it returns
+// how long to wait for one, and takes nothing.
+func (e *executor[T]) admit(t task[T]) time.Duration {
+	if limiter := e.keyLimiters[t.options.rateKey]; limiter != nil && t.options.rateKey != "" {
+		return limiter.Reserve()
+	}
+	return 0
+}
+
+func (e *executor[T]) execute(jobFunc ContextJobFunc[T], t task[T]) {
+	e.busy.Add(1)
+	defer e.busy.Add(-1)
+	defer e.queue.done()
+
+	if t.run != nil {
+		jobFunc = t.run
+	}
+
+	t.attempt++
+	err := e.attempt(jobFunc, t)
+	if err == nil {
+		if t.result != nil {
+			t.result(nil)
+		}
+		return
+	}
+
+	if e.retry(t, err) {
+		return
+	}
+
+	jobErr := &JobError[T]{Job: t.job, Attempts: t.attempt, Err: err}
+	if t.result != nil {
+		t.result(jobErr)
+	}
+
+	logger.Printf("Error when process job -> %s \n", jobErr.Error())
+	if e.errors != nil {
+		e.errors <- jobErr
+	}
+}
+
+// attempt runs jobFunc once with the timeout of t. A failure after the timeout is reported
+// as ErrJobTimeout, but not one caused by the pool stopping.
+func (e *executor[T]) attempt(jobFunc ContextJobFunc[T], t task[T]) error {
+	timeout := e.config.JobTimeout
+	if t.options.timeout > 0 {
+		timeout = t.options.timeout
+	}
+
+	ctx := e.ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	err := e.call(ctx, jobFunc, t.job)
+	if err != nil && e.ctx.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %s: %w", ErrJobTimeout, timeout, err)
+	}
+
+	return err
+}
+
+// call runs jobFunc, converting a panic into a *PanicError so the worker keeps serving
+func (e *executor[T]) call(ctx context.Context, jobFunc ContextJobFunc[T], job T) (err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			panicErr := &PanicError{Value: value, Stack: debug.Stack()}
+			if e.config.PanicHandler != nil {
+				e.config.PanicHandler(panicErr)
+			} else {
+				logger.Printf("Job panicked -> %v \n%s", value, panicErr.Stack)
+			}
+			err = panicErr
+		}
+	}()
+
+	return jobFunc(ctx, job)
+}
+
+// retry requeues t after its backoff when it has attempts left and err is retryable.
+// The delay is waited outside of the workers.
+func (e *executor[T]) retry(t task[T], err error) bool {
+	maxAttempts, backoff, retryable := e.config.MaxAttempts, e.config.Backoff, e.config.Retryable
+	if t.options.maxAttempts > 0 {
+		maxAttempts = t.options.maxAttempts
+	}
+	if t.options.backoff != nil {
+		backoff = t.options.backoff
+	}
+	if t.options.retryable != nil {
+		retryable = t.options.retryable
+	}
+	if retryable == nil {
+		retryable = isRetryable
+	}
+
+	if t.attempt >= maxAttempts || !retryable(err) {
+		return false
+	}
+
+	if backoff == nil {
+		backoff = defaultRetryBackoff
+	}
+
+	delay := backoff.Next(t.attempt - 1)
+	logger.Printf("Job attempt %d failed, retrying in %s -> %s \n", t.attempt, delay, err.Error())
+	e.queue.schedule(t, t.options.priority, delay)
+
+	return true
+}
@@ -0,0 +1,193 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PCS-Indonesia/pakakeh/httpclient"
+)
+
+var errPartnerDown = errors.New("partner down")
+
+// flakyJob fails until it has been run Failures times
+type flakyJob struct {
+	ID       string
+	Failures int
+}
+
+type flakyRecorder struct {
+	mu   sync.Mutex
+	runs []string
+}
+
+func (r *flakyRecorder) handler() JobHandlerFunc[flakyJob] {
+	return func() JobFunc[flakyJob] {
+		return func(j flakyJob) error {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+
+			r.runs = append(r.runs, j.ID)
+			count := 0
+			for _, id := range r.runs {
+				if id == j.ID {
+					count++
+				}
+			}
+			if count <= j.Failures {
+				if j.ID == "permanent" {
+					return Permanent(errPartnerDown)
+				}
+				return errPartnerDown
+			}
+			return nil
+		}
+	}
+}
+
+func (r *flakyRecorder) snapshot() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.runs...)
+}
+
+func retryPool(t *testing.T, recorder *flakyRecorder, options ...Option) (*Pool[flakyJob], chan struct{}) {
+	done := make(chan struct{})
+	options = append([]Option{func(c *Config) error {
+		c.WorkerNum = 1
+		c.Errors = true
+		c.MaxAttempts = 3
+		c.Backoff = httpclient.NewConstantBackoff(5*time.Millisecond, 0)
+		return nil
+	}}, options...)
+
+	p := New(done, recorder.handler(), options...)
+	p.Start()
+	return p, done
+}
+
+func TestRetryUntilSuccess(t *testing.T) {
+	recorder := &flakyRecorder{}
+	p, done := retryPool(t, recorder)
+
+	require.NoError(t, p.Submit(context.Background(), flakyJob{ID: "inquiry", Failures: 2}))
+
+	close(done)
+	waitClosed(t, p)
+
+	assert.Equal(t, []string{"inquiry", "inquiry", "inquiry"}, recorder.snapshot())
+	_, open := <-p.Errors
+	assert.False(t, open, "a job that succeeded on retry must not be reported")
+}
+
+func TestRetryExhaustedAndPermanent(t *testing.T) {
+	recorder := &flakyRecorder{}
+	p, done := retryPool(t, recorder)
+
+	require.NoError(t, p.Submit(context.Background(), flakyJob{ID: "permanent", Failures: 5}))
+	require.NoError(t, p.Submit(context.Background(), flakyJob{ID: "exhausted", Failures: 5}, WithRetry(2, nil)))
+
+	var jobErrs []*JobError[flakyJob]
+	for i := 0; i < 2; i++ {
+		var jobErr *JobError[flakyJob]
+		require.ErrorAs(t, <-p.Errors, &jobErr)
+		assert.ErrorIs(t, jobErr, errPartnerDown)
+		jobErrs = append(jobErrs, jobErr)
+	}
+
+	close(done)
+	waitClosed(t, p)
+
+	assert.Equal(t, "permanent", jobErrs[0].Job.ID)
+	assert.Equal(t, 1, jobErrs[0].Attempts)
+	assert.Equal(t, "exhausted", jobErrs[1].Job.ID)
+	assert.Equal(t, 2, jobErrs[1].Attempts)
+	assert.Contains(t, jobErrs[1].Error(), "after 2 attempts")
+}
+
+func TestRetryDoesNotHoldWorker(t *testing.T) {
+	recorder := &flakyRecorder{}
+	p, done := retryPool(t, recorder, func(c *Config) error {
+		c.Backoff = httpclient.NewConstantBackoff(50*time.Millisecond, 0)
+		c.Retryable = func(err error) bool { return errors.Is(err, errPartnerDown) }
+		return nil
+	})
+
+	require.NoError(t, p.Submit(context.Background(), flakyJob{ID: "slow-retry", Failures: 1}))
+	require.Eventually(t, func() bool { return len(recorder.snapshot()) == 1 }, time.Second, time.Millisecond)
+	require.NoError(t, p.Submit(context.Background(), flakyJob{ID: "next"}))
+
+	// closing waits for the pending retry
+	close(done)
+	waitClosed(t, p)
+
+	assert.Equal(t, []string{"slow-retry", "next", "slow-retry"}, recorder.snapshot())
+}
+
+func TestSubmitWithResultRetries(t *testing.T) {
+	p, stop := inquiryPool(t)
+	defer stop()
+
+	attempts := 0
+	future, err := SubmitWithResult(context.Background(), p, balanceInquiry{Account: "123"}, func(job balanceInquiry) (int, error) {
+		attempts++
+		if attempts < 3 {
+			return 0, errPartnerDown
+		}
+		return 7000, nil
+	}, WithRetry(3, httpclient.NewConstantBackoff(time.Millisecond, 0)))
+	require.NoError(t, err)
+
+	value, err := future.Get(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 7000, value)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestWithDone(t *testing.T) {
+	recorder := &flakyRecorder{}
+	p, done := retryPool(t, recorder, func(c *Config) error {
+		c.Errors = false
+		return nil
+	})
+
+	outcomes := make(chan error, 2)
+	report := WithDone(func(err error) { outcomes <- err })
+
+	require.NoError(t, p.Submit(context.Background(), flakyJob{ID: "inquiry", Failures: 1}, report))
+	require.NoError(t, p.Submit(context.Background(), flakyJob{ID: "exhausted", Failures: 5}, report))
+
+	close(done)
+	waitClosed(t, p)
+
+	var succeeded int
+	for i := 0; i < 2; i++ {
+		err := <-outcomes
+		if err == nil {
+			succeeded++
+			continue
+		}
+		var jobErr *JobError[flakyJob]
+		require.ErrorAs(t, err, &jobErr)
+		assert.Equal(t, "exhausted", jobErr.Job.ID)
+		assert.Equal(t, 3, jobErr.Attempts)
+	}
+	assert.Equal(t, 1, succeeded)
+}
+
+func TestWithDoneCalledInOrder(t *testing.T) {
+	var calls []string
+	queued := newTask("inquiry", []JobOption{
+		WithDone(func(error) { calls = append(calls, "caller") }),
+		WithDone(nil),
+		WithDone(func(error) { calls = append(calls, "scheduler") }),
+	})
+
+	queued.result(nil)
+	assert.Equal(t, []string{"caller", "scheduler"}, calls)
+}
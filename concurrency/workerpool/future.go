@@ -0,0 +1,152 @@
+package workerpool
+
+import (
+	"context"
+)
+
+// ResultFunc processes a job submitted with SubmitWithResult and returns its result
+type ResultFunc[T, R any] func(T) (R, error)
+
+// ContextResultFunc processes a job submitted with SubmitWithResultContext and returns its
+// result. ctx is done when the attempt times out or the pool is stopped, like ContextJobFunc.
+type ContextResultFunc[T, R any] func(context.Context, T) (R, error)
+
+// Future is the pending result of a job submitted with SubmitWithResult
+type Future[R any] struct {
+	done  chan struct{}
+	value R
+	err   error
+}
+
+// Result is the outcome of a job returned by Collect and AsCompleted. Index is the
+// position of its future in the slice given to them.
+type Result[R any] struct {
+	Index int
+	Value R
+	Err   error
+}
+
+func newFuture[R any]() *Future[R] {
+	return &Future[R]{done: make(chan struct{})}
+}
+
+// Get waits for the job to finish and returns its result. It returns ctx.Err() when ctx
+// is done first, the job keeps running and Get can be called again.
+func (f *Future[R]) Get(ctx context.Context) (R, error) {
+	select {
+	case <-f.done:
+		return f.value, f.err
+	default:
+	}
+
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		var zero R
+		return zero, ctx.Err()
+	}
+}
+
+// Done is closed when the result is available
+func (f *Future[R]) Done() <-chan struct{} {
+	return f.done
+}
+
+func (f *Future[R]) resolve(value R, err error) {
+	f.value, f.err = value, err
+	close(f.done)
+}
+
+// SubmitWithResult queues job on p like Submit, but processes it with fn instead of the
+// pool JobFunc and returns a Future for its result. A failed job resolves the future with
+// a *JobError once it has no attempt left, and is reported like any other job.
+func SubmitWithResult[T, R any](ctx context.Context, p *Pool[T], job T, fn ResultFunc[T, R], opts ...JobOption) (*Future[R], error) {
+	return SubmitWithResultContext(ctx, p, job, func(_ context.Context, job T) (R, error) {
+		return fn(job)
+	}, opts...)
+}
+
+// SubmitWithResultContext is SubmitWithResult with a fn that receives the context of the
+// attempt, so it can stop when the job times out or the pool is stopped.
+func SubmitWithResultContext[T, R any](ctx context.Context, p *Pool[T], job T, fn ContextResultFunc[T, R], opts ...JobOption) (*Future[R], error) {
+	future := newFuture[R]()
+
+	var value R
+	t := newTask(job, opts)
+	t.run = func(ctx context.Context, job T) error {
+		var err error
+		value, err = fn(ctx, job)
+		return err
+	}
+	done := t.result
+	t.result = func(err error) {
+		if err != nil {
+			var zero R
+			value = zero
+		}
+		future.resolve(value, err)
+		if done != nil {
+			done(err)
+		}
+	}
+
+	if err := p.queue.push(ctx, t, t.options.priority, true); err != nil {
+		return nil, err
+	}
+
+	return future, nil
+}
+
+// Collect waits for every future and returns their results in the same order. When ctx
+// is done first it returns ctx.Err(), the unfinished results then carry that error.
+func Collect[R any](ctx context.Context, futures []*Future[R]) ([]Result[R], error) {
+	results := make([]Result[R], len(futures))
+
+	complete := true
+	for i, future := range futures {
+		value, err := future.Get(ctx)
+		if ctx.Err() != nil && err == ctx.Err() {
+			complete = false
+		}
+		results[i] = Result[R]{Index: i, Value: value, Err: err}
+	}
+
+	if !complete {
+		return results, ctx.Err()
+	}
+
+	return results, nil
+}
+
+// AsCompleted returns the results of futures in the order the jobs finish. The channel is
+// closed once every result was sent, or when ctx is done.
+func AsCompleted[R any](ctx context.Context, futures []*Future[R]) <-chan Result[R] {
+	results := make(chan Result[R], len(futures))
+
+	finished := make(chan int, len(futures))
+	for i, future := range futures {
+		go func(i int, future *Future[R]) {
+			select {
+			case <-future.done:
+				finished <- i
+			case <-ctx.Done():
+			}
+		}(i, future)
+	}
+
+	go func() {
+		defer close(results)
+
+		for range futures {
+			select {
+			case i := <-finished:
+				results <- Result[R]{Index: i, Value: futures[i].value, Err: futures[i].err}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return results
+}
@@ -0,0 +1,137 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type balanceInquiry struct {
+	Account string
+	Delay   time.Duration
+}
+
+func inquiryPool(t *testing.T) (*Pool[balanceInquiry], func()) {
+	done := make(chan struct{})
+	handler := func() JobFunc[balanceInquiry] {
+		return func(balanceInquiry) error {
+			return errors.New("pool handler must not be used")
+		}
+	}
+
+	p := New(done, handler, func(c *Config) error {
+		c.WorkerNum = 8
+		return nil
+	})
+	p.Start()
+
+	return p, func() {
+		close(done)
+		waitClosed(t, p)
+	}
+}
+
+func inquire(job balanceInquiry) (int, error) {
+	time.Sleep(job.Delay)
+	if job.Account == "blocked" {
+		return 0, errors.New("account blocked")
+	}
+	return len(job.Account) * 1000, nil
+}
+
+func TestSubmitWithResultAndCollect(t *testing.T) {
+	p, stop := inquiryPool(t)
+	defer stop()
+
+	accounts := []string{"1", "22", "blocked", "4444", "55555"}
+	futures := make([]*Future[int], len(accounts))
+	for i, account := range accounts {
+		// later jobs finish first
+		job := balanceInquiry{Account: account, Delay: time.Duration(len(accounts)-i) * 5 * time.Millisecond}
+
+		future, err := SubmitWithResult(context.Background(), p, job, inquire)
+		require.NoError(t, err)
+		futures[i] = future
+	}
+
+	results, err := Collect(context.Background(), futures)
+	require.NoError(t, err)
+	require.Len(t, results, len(accounts))
+
+	for i, result := range results {
+		assert.Equal(t, i, result.Index)
+		if accounts[i] == "blocked" {
+			assert.EqualError(t, result.Err, "account blocked")
+			continue
+		}
+		assert.NoError(t, result.Err)
+		assert.Equal(t, len(accounts[i])*1000, result.Value)
+	}
+
+	value, err := futures[4].Get(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 5000, value)
+}
+
+func TestAsCompleted(t *testing.T) {
+	p, stop := inquiryPool(t)
+	defer stop()
+
+	var futures []*Future[int]
+	for i := 3; i > 0; i-- {
+		future, err := SubmitWithResult(context.Background(), p, balanceInquiry{Account: fmt.Sprint(i), Delay: time.Duration(i) * 40 * time.Millisecond}, inquire)
+		require.NoError(t, err)
+		futures = append(futures, future)
+	}
+
+	var order []int
+	for result := range AsCompleted(context.Background(), futures) {
+		order = append(order, result.Index)
+	}
+	assert.Equal(t, []int{2, 1, 0}, order)
+}
+
+func TestCollectContextDone(t *testing.T) {
+	p, stop := inquiryPool(t)
+	defer stop()
+
+	fast, err := SubmitWithResult(context.Background(), p, balanceInquiry{Account: "1"}, inquire)
+	require.NoError(t, err)
+	slow, err := SubmitWithResult(context.Background(), p, balanceInquiry{Account: "2", Delay: 200 * time.Millisecond}, inquire)
+	require.NoError(t, err)
+
+	<-fast.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	results, err := Collect(ctx, []*Future[int]{fast, slow})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, 1000, results[0].Value)
+	assert.ErrorIs(t, results[1].Err, context.DeadlineExceeded)
+}
+
+func TestSubmitWithResultContextTimeout(t *testing.T) {
+	p, stop := inquiryPool(t)
+	defer stop()
+
+	future, err := SubmitWithResultContext(context.Background(), p, balanceInquiry{Account: "1"},
+		func(ctx context.Context, job balanceInquiry) (int, error) {
+			<-ctx.Done() // the partner never answers
+			return 0, ctx.Err()
+		}, WithTimeout(10*time.Millisecond))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err = future.Get(ctx)
+	assert.ErrorIs(t, err, ErrJobTimeout)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,64 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPanicRecovered(t *testing.T) {
+	done := make(chan struct{})
+	var processed atomic.Int32
+	var forwarded atomic.Pointer[PanicError]
+
+	handler := func() JobFunc[int] {
+		return func(n int) error {
+			if n == 0 {
+				var m map[string]int
+				m["boom"] = 1
+			}
+			processed.Add(1)
+			return nil
+		}
+	}
+
+	p := New(done, handler, func(c *Config) error {
+		c.WorkerNum = 1
+		c.Errors = true
+		c.MaxAttempts = 3
+		c.PanicHandler = func(err *PanicError) {
+			forwarded.Store(err)
+		}
+		return nil
+	})
+	p.Start()
+
+	require.NoError(t, p.Submit(context.Background(), 0))
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, p.Submit(context.Background(), i))
+	}
+
+	err := <-p.Errors
+	var panicErr *PanicError
+	require.ErrorAs(t, err, &panicErr)
+	assert.Contains(t, panicErr.Error(), "assignment to entry in nil map")
+	assert.Contains(t, string(panicErr.Stack), "panic_test.go")
+
+	var runtimeErr interface{ RuntimeError() }
+	assert.True(t, errors.As(err, &runtimeErr), "the panic value is unwrapped")
+
+	var jobErr *JobError[int]
+	require.ErrorAs(t, err, &jobErr)
+	assert.Equal(t, 1, jobErr.Attempts, "panics are not retried")
+
+	close(done)
+	waitClosed(t, p)
+
+	// the single worker kept serving after the panic
+	assert.Equal(t, int32(3), processed.Load())
+	assert.Same(t, panicErr, forwarded.Load())
+}
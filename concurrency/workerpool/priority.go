@@ -0,0 +1,44 @@
+package workerpool
+
+import (
+	"time"
+)
+
+// Priority is the level of a job, dispatchers always take the queued job of the highest
+// level first. Any value can be used, the constants are only the common levels.
+type Priority int
+
+const (
+	PriorityLow    Priority = -1
+	PriorityNormal Priority = 0
+	PriorityHigh   Priority = 1
+)
+
+// WithPriority sets the priority of the job, default is PriorityNormal. A retried job keeps
+// its priority.
+func WithPriority(priority Priority) JobOption {
+	return func(o *jobOptions) {
+		o.priority = priority
+	}
+}
+
+// queued is a job waiting in the queue
+type queued[T any] struct {
+	job   T
+	since time.Time // when it was queued, for aging
+}
+
+// priorityLevel holds the queued jobs of one priority in FIFO order
+type priorityLevel[T any] struct {
+	priority Priority
+	jobs     []queued[T]
+}
+
+// effective returns the priority of the oldest job of the level once aging is applied.
+// The job gains one level for every aging it waited, aging 0 disables it.
+func (l *priorityLevel[T]) effective(now time.Time, aging time.Duration) Priority {
+	if aging <= 0 {
+		return l.priority
+	}
+	return l.priority + Priority(now.Sub(l.jobs[0].since)/aging)
+}
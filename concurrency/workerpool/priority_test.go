@@ -0,0 +1,76 @@
+package workerpool
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriorityOrder(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+
+	var mu sync.Mutex
+	var order []string
+	handler := func() JobFunc[string] {
+		return func(id string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, id)
+			return nil
+		}
+	}
+
+	p := New(done, handler, func(c *Config) error {
+		c.WorkerNum = 1
+		return nil
+	})
+
+	require.NoError(t, p.TrySubmit("report-1", WithPriority(PriorityLow)))
+	require.NoError(t, p.TrySubmit("inquiry-1"))
+	require.NoError(t, p.TrySubmit("payment-1", WithPriority(PriorityHigh)))
+	require.NoError(t, p.Submit(context.Background(), "inquiry-2", WithPriority(PriorityNormal)))
+	require.NoError(t, p.Submit(context.Background(), "payment-2", WithPriority(PriorityHigh)))
+
+	stats := p.Stats()
+	assert.Equal(t, 5, stats.QueueLen)
+	assert.Equal(t, map[Priority]int{PriorityLow: 1, PriorityNormal: 2, PriorityHigh: 2}, stats.QueueLenByPriority)
+
+	p.Start()
+	p.Wait()
+
+	assert.Equal(t, []string{"payment-1", "payment-2", "inquiry-1", "inquiry-2", "report-1"}, order)
+	assert.Equal(t, map[Priority]int{PriorityLow: 0, PriorityNormal: 0, PriorityHigh: 0}, p.Stats().QueueLenByPriority)
+}
+
+func TestPriorityAging(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		aging time.Duration
+		want  []string
+	}{
+		{name: "disabled", want: []string{"payment", "inquiry", "report"}},
+		{name: "enabled", aging: 10 * time.Millisecond, want: []string{"report", "payment", "inquiry"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			q := newJobQueue[string](10, tc.aging)
+			require.NoError(t, q.push(context.Background(), "report", PriorityLow, false))
+			require.NoError(t, q.push(context.Background(), "inquiry", PriorityNormal, false))
+			require.NoError(t, q.push(context.Background(), "payment", PriorityHigh, false))
+
+			// the report waited long enough to catch up with the payment, and is older
+			now := time.Now()
+			q.levels[2].jobs[0].since = now.Add(-25 * time.Millisecond)
+
+			var got []string
+			for q.size > 0 {
+				got = append(got, q.take(now))
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
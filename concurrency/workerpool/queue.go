@@ -0,0 +1,276 @@
+package workerpool
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// jobQueue is the bounded priority queue between producers and dispatchers, jobs of the
+// same priority are FIFO. Unlike a channel it can be closed while producers are still
+// waiting to send.
+type jobQueue[T any] struct {
+	mu        sync.Mutex
+	levels    []priorityLevel[T] // highest priority first, empty levels are kept
+	size      int                // jobs queued over all levels
+	capacity  int
+	aging     time.Duration // see Config.PriorityAging
+	closed    bool
+	aborted   bool
+	discard   func(T)       // receives the jobs dropped after abort
+	active    int           // items handed out by tryPop and not marked done yet
+	scheduled int           // items waiting for their requeue delay
+	changed   chan struct{} // closed and replaced on every change to wake up the waiters
+}
+
+func newJobQueue[T any](capacity int, aging time.Duration) *jobQueue[T] {
+	return &jobQueue[T]{
+		capacity: Max(capacity, 1),
+		aging:    aging,
+		changed:  make(chan struct{}),
+	}
+}
+
+// push adds job to the queue. When the queue is full it waits for a free slot until ctx
+// is done, or returns ErrQueueFull right away when block is false.
+func (q *jobQueue[T]) push(ctx context.Context, job T, priority Priority, block bool) error {
+	for {
+		q.mu.Lock()
+		if q.closed {
+			q.mu.Unlock()
+			return ErrPoolClosed
+		}
+
+		if q.size < q.capacity {
+			q.append(job, priority)
+			q.broadcast()
+			q.mu.Unlock()
+			return nil
+		}
+
+		if !block {
+			q.mu.Unlock()
+			return ErrQueueFull
+		}
+
+		changed := q.changed
+		q.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// ready waits until a job is queued, without taking it. It returns false once the queue
+// is closed and drained or aborted, or when stop receives a value or is closed.
+// A closed queue is drained when no job is queued, running or waiting to be requeued,
+// as a running job may still be requeued.
+func (q *jobQueue[T]) ready(stop <-chan struct{}) bool {
+	for {
+		q.mu.Lock()
+		if q.size > 0 {
+			q.mu.Unlock()
+			return true
+		}
+
+		if q.aborted || (q.closed && q.active == 0 && q.scheduled == 0) {
+			q.mu.Unlock()
+			return false
+		}
+
+		changed := q.changed
+		q.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-stop:
+			return false
+		}
+	}
+}
+
+// tryPop takes the next job without waiting, the caller must call done once it is processed.
+// It returns false when the queue is empty, e.g. another dispatcher took the job first.
+func (q *jobQueue[T]) tryPop() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.size == 0 {
+		var zero T
+		return zero, false
+	}
+
+	job := q.take(time.Now())
+	q.active++
+	q.broadcast()
+	return job, true
+}
+
+// done marks a job handed out by tryPop as processed
+func (q *jobQueue[T]) done() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.active--
+	q.broadcast()
+}
+
+// schedule requeues job after delay. Requeued jobs were already accepted, so they
+// ignore the capacity and are accepted after close, but not after abort.
+func (q *jobQueue[T]) schedule(job T, priority Priority, delay time.Duration) {
+	q.mu.Lock()
+	if q.aborted {
+		discard := q.discard
+		q.mu.Unlock()
+		discard(job)
+		return
+	}
+	q.scheduled++
+	q.mu.Unlock()
+
+	time.AfterFunc(delay, func() {
+		q.mu.Lock()
+		if q.aborted {
+			// abort already counted the job
+			discard := q.discard
+			q.mu.Unlock()
+			discard(job)
+			return
+		}
+
+		q.scheduled--
+		q.append(job, priority)
+		q.broadcast()
+		q.mu.Unlock()
+	})
+}
+
+// wait blocks until no job is queued, running or waiting to be requeued
+func (q *jobQueue[T]) wait() {
+	for {
+		q.mu.Lock()
+		if q.size == 0 && q.active == 0 && q.scheduled == 0 {
+			q.mu.Unlock()
+			return
+		}
+
+		changed := q.changed
+		q.mu.Unlock()
+
+		<-changed
+	}
+}
+
+// abort closes the queue and drops the queued jobs and the pending requeues, passing each
+// one to discard. It returns how many jobs were dropped, only the first call drops any.
+// The running jobs are not affected, but they are no longer requeued.
+func (q *jobQueue[T]) abort(discard func(T)) int {
+	q.mu.Lock()
+	if q.aborted {
+		q.mu.Unlock()
+		return 0
+	}
+
+	var jobs []T
+	for i := range q.levels {
+		for _, entry := range q.levels[i].jobs {
+			jobs = append(jobs, entry.job)
+		}
+		q.levels[i].jobs = nil
+	}
+	dropped := len(jobs) + q.scheduled
+
+	q.aborted = true
+	q.closed = true
+	q.discard = discard
+	q.size = 0
+	q.scheduled = 0
+	q.broadcast()
+	q.mu.Unlock()
+
+	for _, job := range jobs {
+		discard(job)
+	}
+
+	return dropped
+}
+
+// close rejects new jobs, the jobs already queued are still handed out by tryPop
+func (q *jobQueue[T]) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.closed {
+		q.closed = true
+		q.broadcast()
+	}
+}
+
+// lenByPriority returns the number of queued jobs of every priority seen so far
+func (q *jobQueue[T]) lenByPriority() map[Priority]int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	lens := make(map[Priority]int, len(q.levels))
+	for _, level := range q.levels {
+		lens[level.priority] = len(level.jobs)
+	}
+	return lens
+}
+
+// append queues job at the end of its priority level, mu must be held
+func (q *jobQueue[T]) append(job T, priority Priority) {
+	i := sort.Search(len(q.levels), func(i int) bool {
+		return q.levels[i].priority <= priority
+	})
+	if i == len(q.levels) || q.levels[i].priority != priority {
+		q.levels = append(q.levels, priorityLevel[T]{})
+		copy(q.levels[i+1:], q.levels[i:])
+		q.levels[i] = priorityLevel[T]{priority: priority}
+	}
+
+	q.levels[i].jobs = append(q.levels[i].jobs, queued[T]{job: job, since: time.Now()})
+	q.size++
+}
+
+// take removes the next job, mu must be held and the queue must not be empty. Without
+// aging it is the oldest job of the highest priority. With aging, the level whose oldest
+// job has the highest effective priority goes first, the older job on a tie.
+func (q *jobQueue[T]) take(now time.Time) T {
+	next := -1
+	var best Priority
+	for i := range q.levels {
+		level := &q.levels[i]
+		if len(level.jobs) == 0 {
+			continue
+		}
+
+		effective := level.effective(now, q.aging)
+		if next < 0 || effective > best ||
+			(effective == best && level.jobs[0].since.Before(q.levels[next].jobs[0].since)) {
+			next, best = i, effective
+		}
+
+		if q.aging <= 0 {
+			break // levels are sorted, the first non-empty one wins
+		}
+	}
+
+	level := &q.levels[next]
+	job := level.jobs[0].job
+	level.jobs[0] = queued[T]{}
+	level.jobs = level.jobs[1:]
+	q.size--
+
+	return job
+}
+
+// broadcast must be called with mu held
+func (q *jobQueue[T]) broadcast() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
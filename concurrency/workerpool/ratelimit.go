@@ -0,0 +1,53 @@
+package workerpool
+
+import (
+	"time"
+
+	"github.com/PCS-Indonesia/pakakeh/httpclient"
+)
+
+// RateLimit allows Rate jobs per second with bursts of up to Burst jobs, default Burst is 1.
+// A zero Rate means no limit.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// WithRateKey applies the Config.KeyRateLimits entry of key to the job, e.g. the partner
+// it calls. A key without a limit only gets the pool RateLimit.
+func WithRateKey(key string) JobOption {
+	return func(o *jobOptions) {
+		o.rateKey = key
+	}
+}
+
+// newRateLimiter returns the token bucket of limit, nil when limit has no Rate
+func newRateLimiter(limit RateLimit) *httpclient.RateLimiter {
+	if limit.Rate <= 0 {
+		return nil
+	}
+
+	return httpclient.NewRateLimiter(limit.Rate, limit.Burst)
+}
+
+// acquireToken blocks until it took a token of limiter. It returns false, and takes nothing,
+// when stop or cancel is closed first.
+func acquireToken(limiter *httpclient.RateLimiter, stop, cancel <-chan struct{}) bool {
+	for {
+		delay := limiter.Reserve()
+		if delay == 0 {
+			return true
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-stop:
+			timer.Stop()
+			return false
+		case <-cancel:
+			timer.Stop()
+			return false
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package workerpool
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// timingRecorder records when every job started
+type timingRecorder struct {
+	mu     sync.Mutex
+	starts map[string][]time.Time
+}
+
+func (r *timingRecorder) handler() JobHandlerFunc[string] {
+	return func() JobFunc[string] {
+		return func(job string) error {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.starts[job] = append(r.starts[job], time.Now())
+			return nil
+		}
+	}
+}
+
+func TestPoolRateLimit(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+
+	recorder := &timingRecorder{starts: map[string][]time.Time{}}
+	p := New(done, recorder.handler(), func(c *Config) error {
+		c.WorkerNum = 5
+		c.RateLimit = RateLimit{Rate: 100, Burst: 2}
+		return nil
+	})
+	p.Start()
+
+	start := time.Now()
+	for i := 0; i < 7; i++ {
+		require.NoError(t, p.Submit(context.Background(), "inquiry"))
+	}
+	p.Wait()
+
+	// the burst starts right away, the 5 others wait 10ms each
+	assert.Len(t, recorder.starts["inquiry"], 7)
+	assert.GreaterOrEqual(t, time.Since(start), 45*time.Millisecond)
+}
+
+func TestPoolRateLimitManyDispatchers(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+
+	recorder := &timingRecorder{starts: map[string][]time.Time{}}
+	p := New(done, recorder.handler(), func(c *Config) error {
+		c.WorkerNum = 8
+		c.InitDispatcherNum = 4
+		c.MaxDispatcherNum = 4
+		c.RateLimit = RateLimit{Rate: 50, Burst: 1}
+		return nil
+	})
+	p.Start()
+
+	for i := 0; i < 6; i++ {
+		require.NoError(t, p.Submit(context.Background(), "inquiry"))
+	}
+	p.Wait()
+
+	// the dispatchers never share a token, so every start is about 20ms after the previous one
+	starts := recorder.starts["inquiry"]
+	require.Len(t, starts, 6)
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+	for i := 1; i < len(starts); i++ {
+		assert.GreaterOrEqual(t, starts[i].Sub(starts[i-1]), 15*time.Millisecond, "start %d", i)
+	}
+}
+
+func TestPoolRateLimitAfterIdle(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+
+	recorder := &timingRecorder{starts: map[string][]time.Time{}}
+	p := New(done, recorder.handler(), func(c *Config) error {
+		c.WorkerNum = 8
+		c.InitDispatcherNum = 4
+		c.MaxDispatcherNum = 4
+		c.RateLimit = RateLimit{Rate: 20, Burst: 1}
+		return nil
+	})
+	p.Start()
+
+	// idle dispatchers must not hold a token each, or the burst grows to their number
+	time.Sleep(200 * time.Millisecond)
+	for i := 0; i < 6; i++ {
+		require.NoError(t, p.Submit(context.Background(), "inquiry"))
+	}
+	p.Wait()
+
+	starts := recorder.starts["inquiry"]
+	require.Len(t, starts, 6)
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+	for i := 1; i < len(starts); i++ {
+		assert.GreaterOrEqual(t, starts[i].Sub(starts[i-1]), 40*time.Millisecond, "start %d", i)
+	}
+}
+
+func TestRateLimiterConcurrentAcquire(t *testing.T) {
+	limiter := newRateLimiter(RateLimit{Rate: 5, Burst: 2})
+	stop := make(chan struct{})
+
+	// like dispatchers waiting for a token at the same time
+	var acquired atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if acquireToken(limiter, stop, nil) {
+				acquired.Add(1)
+			}
+		}()
+	}
+
+	// the burst is taken right away, the next token comes after 200ms
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(2), acquired.Load())
+
+	close(stop)
+	wg.Wait()
+	assert.LessOrEqual(t, acquired.Load(), int32(3))
+}
+
+func TestKeyRateLimit(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+
+	recorder := &timingRecorder{starts: map[string][]time.Time{}}
+	p := New(done, recorder.handler(), func(c *Config) error {
+		c.WorkerNum = 1
+		c.KeyRateLimits = map[string]RateLimit{"partner-a": {Rate: 20}}
+		return nil
+	})
+	p.Start()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, p.Submit(context.Background(), "partner-a", WithRateKey("partner-a")))
+	}
+	for i := 0; i < 5; i++ {
+		require.NoError(t, p.Submit(context.Background(), "partner-b", WithRateKey("partner-b")))
+	}
+	p.Wait()
+
+	partnerA, partnerB := recorder.starts["partner-a"], recorder.starts["partner-b"]
+	require.Len(t, partnerA, 3)
+	require.Len(t, partnerB, 5)
+
+	// partner-a waits 50ms between jobs without holding back partner-b
+	assert.GreaterOrEqual(t, partnerA[2].Sub(start), 95*time.Millisecond)
+	assert.True(t, partnerB[4].Before(partnerA[1]), "partner-b jobs must not wait for partner-a")
+}
+
+func TestRateLimitHonorsStop(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+
+	recorder := &timingRecorder{starts: map[string][]time.Time{}}
+	p := New(done, recorder.handler(), func(c *Config) error {
+		c.WorkerNum = 2
+		c.RateLimit = RateLimit{Rate: 0.1}
+		return nil
+	})
+	p.Start()
+
+	require.NoError(t, p.Submit(context.Background(), "inquiry"))
+	require.NoError(t, p.Submit(context.Background(), "inquiry"))
+	require.Eventually(t, func() bool { return p.Stats().QueueLen == 1 }, time.Second, time.Millisecond)
+
+	start := time.Now()
+	assert.Equal(t, 1, p.Stop())
+	waitClosed(t, p)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Len(t, recorder.starts["inquiry"], 1)
+}
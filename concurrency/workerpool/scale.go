@@ -0,0 +1,156 @@
+package workerpool
+
+import (
+	"time"
+)
+
+// ScaleDirection tells whether a ScaleEvent added or retired a dispatcher
+type ScaleDirection string
+
+const (
+	ScaleUp   ScaleDirection = "up"
+	ScaleDown ScaleDirection = "down"
+)
+
+// ScaleEvent is sent to Config.OnScale every time the supervisor changes the number of dispatchers
+type ScaleEvent struct {
+	Direction   ScaleDirection
+	From        int // dispatchers before the change
+	To          int // dispatchers after the change
+	QueueLen    int
+	Utilization float64 // share of busy workers when the decision was made, from 0 to 1
+}
+
+// Stats is a snapshot of the pool load
+type Stats struct {
+	Dispatchers int
+	Workers     int
+	BusyWorkers int
+	QueueLen    int
+	// QueueLenByPriority is the number of queued jobs of every priority seen so far
+	QueueLenByPriority map[Priority]int
+}
+
+// Utilization returns the share of busy workers, from 0 to 1
+func (s Stats) Utilization() float64 {
+	if s.Workers == 0 {
+		return 0
+	}
+	return float64(s.BusyWorkers) / float64(s.Workers)
+}
+
+// Stats returns the current load of the pool
+func (p *Pool[T]) Stats() Stats {
+	p.mu.Lock()
+	dispatchers := p.poolNum
+	p.mu.Unlock()
+
+	byPriority := p.queue.lenByPriority()
+	queueLen := 0
+	for _, n := range byPriority {
+		queueLen += n
+	}
+
+	return Stats{
+		Dispatchers:        dispatchers,
+		Workers:            dispatchers * p.config.WorkerNum,
+		BusyWorkers:        int(p.executor.busy.Load()),
+		QueueLen:           queueLen,
+		QueueLenByPriority: byPriority,
+	}
+}
+
+// supervise adds dispatchers while jobs are queued and the workers are busy, and retires
+// them down to InitDispatcherNum once the pool stayed quiet for ScaleCoolDown
+func (p *Pool[T]) supervise() {
+	interval := p.config.ScaleInterval
+	if interval <= 0 {
+		interval = DefaultConfig.ScaleInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-p.draining:
+			return
+		}
+
+		if event, ok := p.autoscale(time.Now()); ok && p.config.OnScale != nil {
+			p.config.OnScale(event)
+		}
+	}
+}
+
+func (p *Pool[T]) autoscale(now time.Time) (ScaleEvent, bool) {
+	stats := p.Stats()
+	utilization := stats.Utilization()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed || p.stopping {
+		return ScaleEvent{}, false
+	}
+
+	event := ScaleEvent{From: p.poolNum, QueueLen: stats.QueueLen, Utilization: utilization}
+
+	switch {
+	case p.poolNum > Max(p.config.MaxDispatcherNum, 1):
+		// SetMaxPoolNum lowered the maximum
+		p.retireDispatcher()
+		event.Direction = ScaleDown
+
+	case stats.QueueLen > 0 && utilization >= p.config.ScaleUpUtilization && p.poolNum < p.config.MaxDispatcherNum:
+		p.addDispatcher()
+		p.quietSince = time.Time{}
+		event.Direction = ScaleUp
+
+	case stats.QueueLen == 0 && utilization <= p.config.ScaleDownUtilization && p.poolNum > p.config.InitDispatcherNum:
+		if p.quietSince.IsZero() {
+			p.quietSince = now
+			return ScaleEvent{}, false
+		}
+		if now.Sub(p.quietSince) < p.config.ScaleCoolDown {
+			return ScaleEvent{}, false
+		}
+		p.retireDispatcher()
+		p.quietSince = now // the next one waits for a full cool-down again
+		event.Direction = ScaleDown
+
+	default:
+		p.quietSince = time.Time{}
+		return ScaleEvent{}, false
+	}
+
+	event.To = p.poolNum
+	return event, true
+}
+
+// addDispatcher starts a dispatcher, p.mu must be held
+func (p *Pool[T]) addDispatcher() {
+	retire := make(chan struct{})
+	p.dispatchers = append(p.dispatchers, retire)
+	p.poolNum++
+
+	p.wg.Add(1)
+	var jobFunc ContextJobFunc[T]
+	if p.contextHandler != nil {
+		jobFunc = p.contextHandler()
+	} else {
+		jobFunc = withoutContext(p.JobHandlerFunc())
+	}
+
+	d := NewDispatcher(retire, p.wg, p.config.WorkerNum, p.executor, jobFunc)
+	d.Dispatch()
+}
+
+// retireDispatcher stops the newest dispatcher once its running jobs are done, p.mu must be held
+func (p *Pool[T]) retireDispatcher() {
+	last := len(p.dispatchers) - 1
+	close(p.dispatchers[last])
+	p.dispatchers = p.dispatchers[:last]
+	p.poolNum--
+}
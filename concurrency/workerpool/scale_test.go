@@ -0,0 +1,113 @@
+package workerpool
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoscale(t *testing.T) {
+	done := make(chan struct{})
+	release := make(chan struct{})
+
+	handler := func() JobFunc[int] {
+		return func(int) error {
+			<-release
+			return nil
+		}
+	}
+
+	mu := &sync.Mutex{}
+	var events []ScaleEvent
+	p := New(done, handler, func(c *Config) error {
+		c.InitDispatcherNum = 1
+		c.MaxDispatcherNum = 3
+		c.WorkerNum = 2
+		c.ScaleInterval = 5 * time.Millisecond
+		c.ScaleCoolDown = 20 * time.Millisecond
+		c.OnScale = func(event ScaleEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, event)
+		}
+		return nil
+	})
+	p.Start()
+
+	for i := 0; i < 20; i++ {
+		require.NoError(t, p.Submit(context.Background(), i))
+	}
+
+	require.Eventually(t, func() bool { return p.Stats().BusyWorkers == 6 }, time.Second, time.Millisecond)
+
+	stats := p.Stats()
+	assert.Equal(t, 3, stats.Dispatchers)
+	assert.Equal(t, 6, stats.Workers)
+	assert.Equal(t, 6, stats.BusyWorkers)
+	assert.Equal(t, 14, stats.QueueLen)
+	assert.Equal(t, 1.0, stats.Utilization())
+
+	close(release)
+	require.Eventually(t, func() bool { return p.GetSize() == 1 }, time.Second, time.Millisecond)
+
+	close(done)
+	waitClosed(t, p)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, events, 4)
+	assert.Equal(t, ScaleEvent{Direction: ScaleUp, From: 1, To: 2, QueueLen: events[0].QueueLen, Utilization: 1}, events[0])
+	assert.Equal(t, ScaleUp, events[1].Direction)
+	assert.Equal(t, 3, events[1].To)
+	assert.Equal(t, ScaleEvent{Direction: ScaleDown, From: 3, To: 2}, events[2])
+	assert.Equal(t, ScaleEvent{Direction: ScaleDown, From: 2, To: 1}, events[3])
+}
+
+func TestStopDispatchAndSetMaxPoolNum(t *testing.T) {
+	done := make(chan struct{})
+	var sum sumCounter
+
+	p := New(done, sum.handler(), func(c *Config) error {
+		c.InitDispatcherNum = 3
+		c.ScaleInterval = 5 * time.Millisecond
+		return nil
+	})
+	p.Start()
+	assert.Equal(t, 3, p.GetSize())
+
+	p.StopDispatch(5)
+	assert.Equal(t, 1, p.GetSize(), "at least one dispatcher is kept")
+
+	p.SetMaxPoolNum(1)
+	require.NoError(t, p.Submit(context.Background(), 2))
+
+	close(done)
+	waitClosed(t, p)
+	assert.Equal(t, 2, sum.total())
+}
+
+type sumCounter struct {
+	mu  sync.Mutex
+	sum int
+}
+
+func (s *sumCounter) handler() JobHandlerFunc[int] {
+	return func() JobFunc[int] {
+		return func(n int) error {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.sum += n
+			return nil
+		}
+	}
+}
+
+func (s *sumCounter) total() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sum
+}
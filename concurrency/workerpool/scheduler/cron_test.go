@@ -0,0 +1,114 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCronNext(t *testing.T) {
+	jakarta, err := time.LoadLocation("Asia/Jakarta")
+	require.NoError(t, err)
+
+	// Monday 19 October 2026, 10:07:30 in Jakarta
+	from := time.Date(2026, 10, 19, 10, 7, 30, 0, jakarta)
+
+	for _, tc := range []struct {
+		spec string
+		want []time.Time
+	}{
+		{
+			spec: "*/15 * * * *",
+			want: []time.Time{
+				time.Date(2026, 10, 19, 10, 15, 0, 0, jakarta),
+				time.Date(2026, 10, 19, 10, 30, 0, 0, jakarta),
+			},
+		},
+		{
+			spec: "0 23 * * *",
+			want: []time.Time{
+				time.Date(2026, 10, 19, 23, 0, 0, 0, jakarta),
+				time.Date(2026, 10, 20, 23, 0, 0, 0, jakarta),
+			},
+		},
+		{
+			spec: "30 8-9 * * mon-fri",
+			want: []time.Time{
+				time.Date(2026, 10, 20, 8, 30, 0, 0, jakarta),
+				time.Date(2026, 10, 20, 9, 30, 0, 0, jakarta),
+				time.Date(2026, 10, 21, 8, 30, 0, 0, jakarta),
+			},
+		},
+		{
+			// either the 1st of the month or a Sunday
+			spec: "0 0 1 * 7",
+			want: []time.Time{
+				time.Date(2026, 10, 25, 0, 0, 0, 0, jakarta),
+				time.Date(2026, 11, 1, 0, 0, 0, 0, jakarta),
+				time.Date(2026, 11, 8, 0, 0, 0, 0, jakarta),
+			},
+		},
+		{
+			spec: "@monthly",
+			want: []time.Time{
+				time.Date(2026, 11, 1, 0, 0, 0, 0, jakarta),
+				time.Date(2026, 12, 1, 0, 0, 0, 0, jakarta),
+			},
+		},
+		{
+			spec: "0 0 29 feb *",
+			want: []time.Time{time.Date(2028, 2, 29, 0, 0, 0, 0, jakarta)},
+		},
+		{
+			spec: "0 0 30 2 *",
+			want: []time.Time{{}},
+		},
+	} {
+		t.Run(tc.spec, func(t *testing.T) {
+			schedule, err := ParseCron(tc.spec, jakarta)
+			require.NoError(t, err)
+
+			next := from
+			for _, want := range tc.want {
+				next = schedule.Next(next)
+				assert.True(t, want.Equal(next), "want %s, got %s", want, next)
+			}
+		})
+	}
+}
+
+func TestCronTimeZone(t *testing.T) {
+	schedule, err := ParseCron("CRON_TZ=Asia/Jakarta 0 23 * * *", time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, "Asia/Jakarta", schedule.Location().String())
+
+	// 23:00 in Jakarta is 16:00 UTC
+	next := schedule.Next(time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2026, 10, 19, 16, 0, 0, 0, time.UTC), next.UTC())
+
+	schedule, err = ParseCron("0 23 * * *", nil)
+	require.NoError(t, err)
+	assert.Equal(t, time.Local, schedule.Location())
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * foo *",
+		"CRON_TZ=Mars/Olympus 0 0 * * *",
+	} {
+		_, err := ParseCron(spec, nil)
+		assert.Error(t, err, spec)
+	}
+}
@@ -0,0 +1,367 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/PCS-Indonesia/pakakeh/concurrency/workerpool"
+)
+
+var logger = log.New(os.Stdout, "scheduler:", log.LstdFlags)
+
+// OverlapPolicy decides what happens when an entry is due while its previous run has not
+// finished yet. A run is finished once its job succeeded or failed for good in the pool.
+type OverlapPolicy int
+
+const (
+	// OverlapAllow submits the run anyway, runs of the entry may then run at the same time
+	OverlapAllow OverlapPolicy = iota
+	// OverlapSkip drops the run
+	OverlapSkip
+	// OverlapQueue submits the run once the previous ones finished, every run is kept
+	OverlapQueue
+)
+
+// EntryID identifies an entry of a Scheduler
+type EntryID int
+
+// Entry is a snapshot of a scheduled job
+type Entry struct {
+	ID       EntryID
+	Schedule Schedule
+	Next     time.Time // next run time before jitter
+	Prev     time.Time // last run time before jitter, zero until the first run
+	Running  int       // runs submitted to the pool and not finished yet
+	Queued   int       // runs waiting for the previous ones with OverlapQueue
+}
+
+// Config configures a Scheduler
+type Config struct {
+	// Location is the time zone of the cron expressions without a CRON_TZ prefix,
+	// default is time.Local
+	Location *time.Location
+	// Clock is the time source, default is the system clock. Use a FakeClock in tests.
+	Clock Clock
+	// Jitter delays every run by a random duration up to Jitter, so entries due at the same
+	// time do not hit a partner all at once. It should stay below the entry interval.
+	Jitter time.Duration
+	// Overlap is the default policy of the entries, default is OverlapAllow
+	Overlap OverlapPolicy
+	// OnError is called when a run could not be submitted to the pool, default logs the error
+	OnError func(entry Entry, err error)
+}
+
+// EntryOption customizes a single entry
+type EntryOption func(*entryOptions)
+
+type entryOptions struct {
+	overlap    OverlapPolicy
+	jitter     time.Duration
+	jobOptions []workerpool.JobOption
+}
+
+// WithOverlap overrides the Config Overlap policy for the entry
+func WithOverlap(policy OverlapPolicy) EntryOption {
+	return func(o *entryOptions) {
+		o.overlap = policy
+	}
+}
+
+// WithJitter overrides the Config Jitter for the entry, 0 disables it
+func WithJitter(jitter time.Duration) EntryOption {
+	return func(o *entryOptions) {
+		o.jitter = jitter
+	}
+}
+
+// WithJobOptions sets the workerpool options of every job submitted for the entry,
+// e.g. its priority or retries
+func WithJobOptions(opts ...workerpool.JobOption) EntryOption {
+	return func(o *entryOptions) {
+		o.jobOptions = opts
+	}
+}
+
+type entry[T any] struct {
+	id       EntryID
+	schedule Schedule
+	job      T
+	options  entryOptions
+	next     time.Time
+	due      time.Time // next plus jitter
+	prev     time.Time
+	running  int
+	queued   int
+}
+
+func (e *entry[T]) snapshot() Entry {
+	return Entry{
+		ID:       e.id,
+		Schedule: e.schedule,
+		Next:     e.next,
+		Prev:     e.prev,
+		Running:  e.running,
+		Queued:   e.queued,
+	}
+}
+
+// Scheduler submits jobs to a workerpool.Pool on cron schedules, or once at a given time.
+// The pool processes the jobs, the scheduler only decides when they are submitted.
+type Scheduler[T any] struct {
+	pool   *workerpool.Pool[T]
+	config Config
+	wake   chan struct{}
+
+	mu      sync.Mutex
+	entries map[EntryID]*entry[T]
+	lastID  EntryID
+	ctx     context.Context // of Run, used by the runs submitted after a previous one finished
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+// New returns a Scheduler submitting to pool, call Run to start it
+func New[T any](pool *workerpool.Pool[T], config Config) (*Scheduler[T], error) {
+	if pool == nil {
+		return nil, errors.New("scheduler pool is required")
+	}
+
+	if config.Location == nil {
+		config.Location = time.Local
+	}
+	if config.Clock == nil {
+		config.Clock = realClock{}
+	}
+	if config.OnError == nil {
+		config.OnError = func(entry Entry, err error) {
+			logger.Printf("Error when submit entry %d -> %s \n", entry.ID, err.Error())
+		}
+	}
+
+	return &Scheduler[T]{
+		pool:    pool,
+		config:  config,
+		wake:    make(chan struct{}, 1),
+		entries: make(map[EntryID]*entry[T]),
+		ctx:     context.Background(),
+	}, nil
+}
+
+// Cron submits job on every time matching the cron expression spec, see ParseCron
+func (s *Scheduler[T]) Cron(spec string, job T, opts ...EntryOption) (EntryID, error) {
+	schedule, err := ParseCron(spec, s.config.Location)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.Add(schedule, job, opts...), nil
+}
+
+// Add submits job on every time given by schedule
+func (s *Scheduler[T]) Add(schedule Schedule, job T, opts ...EntryOption) EntryID {
+	return s.add(schedule, schedule.Next(s.config.Clock.Now()), job, opts)
+}
+
+// At submits job once at the given time, right away when it is already past
+func (s *Scheduler[T]) At(at time.Time, job T, opts ...EntryOption) EntryID {
+	return s.add(runAt(at), at, job, opts)
+}
+
+// After submits job once after delay
+func (s *Scheduler[T]) After(delay time.Duration, job T, opts ...EntryOption) EntryID {
+	return s.At(s.config.Clock.Now().Add(delay), job, opts...)
+}
+
+func (s *Scheduler[T]) add(schedule Schedule, next time.Time, job T, opts []EntryOption) EntryID {
+	options := entryOptions{overlap: s.config.Overlap, jitter: s.config.Jitter}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	s.mu.Lock()
+	s.lastID++
+	e := &entry[T]{id: s.lastID, schedule: schedule, job: job, options: options}
+	if !next.IsZero() {
+		e.next, e.due = next, next.Add(e.jitter())
+		s.entries[e.id] = e
+	}
+	s.mu.Unlock()
+
+	s.notify()
+	return e.id
+}
+
+// Remove stops scheduling the entry, its runs already submitted are not affected.
+// It returns false when the entry does not exist or a one-off entry already ran.
+func (s *Scheduler[T]) Remove(id EntryID) bool {
+	s.mu.Lock()
+	_, ok := s.entries[id]
+	delete(s.entries, id)
+	s.mu.Unlock()
+
+	s.notify()
+	return ok
+}
+
+// Entries returns the scheduled entries ordered by ID
+func (s *Scheduler[T]) Entries() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		entries = append(entries, e.snapshot())
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ID < entries[j].ID
+	})
+
+	return entries
+}
+
+// Run submits the due entries to the pool until ctx is done, then waits for the pending
+// submissions and returns ctx.Err(). A run missed while the scheduler was not running is
+// not caught up, a late entry runs once and is then scheduled from the current time.
+// Run must not be called more than once.
+func (s *Scheduler[T]) Run(ctx context.Context) error {
+	s.mu.Lock()
+	s.ctx = ctx
+	s.mu.Unlock()
+
+	for {
+		wait := s.runDue()
+
+		var timer Timer
+		var fired <-chan time.Time
+		if wait >= 0 {
+			timer = s.config.Clock.NewTimer(wait)
+			fired = timer.C()
+		}
+
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+
+			// no run is submitted once stopped, so wg.Add never races with wg.Wait
+			s.mu.Lock()
+			s.stopped = true
+			s.mu.Unlock()
+
+			s.wg.Wait()
+			return ctx.Err()
+		case <-fired:
+		case <-s.wake:
+			if timer != nil {
+				timer.Stop()
+			}
+		}
+	}
+}
+
+// runDue starts the due entries and returns the wait until the next one, -1 when there is none
+func (s *Scheduler[T]) runDue() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.config.Clock.Now()
+	var earliest time.Time
+	for id, e := range s.entries {
+		if !e.due.After(now) {
+			e.prev = e.next
+			s.start(e)
+
+			e.next = e.schedule.Next(now)
+			if e.next.IsZero() {
+				delete(s.entries, id)
+				continue
+			}
+			e.due = e.next.Add(e.jitter())
+		}
+
+		if earliest.IsZero() || e.due.Before(earliest) {
+			earliest = e.due
+		}
+	}
+
+	if earliest.IsZero() {
+		return -1
+	}
+	return earliest.Sub(now)
+}
+
+// start applies the overlap policy to a due run, s.mu must be held
+func (s *Scheduler[T]) start(e *entry[T]) {
+	if e.running > 0 {
+		switch e.options.overlap {
+		case OverlapSkip:
+			logger.Printf("Entry %d skipped, its previous run has not finished \n", e.id)
+			return
+		case OverlapQueue:
+			e.queued++
+			return
+		}
+	}
+
+	s.submit(e)
+}
+
+// submit hands a run of e to the pool without blocking the scheduler, s.mu must be held
+func (s *Scheduler[T]) submit(e *entry[T]) {
+	if s.stopped {
+		return
+	}
+
+	e.running++
+	ctx := s.ctx
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		// WithDone adds to a callback given with WithJobOptions instead of replacing it
+		opts := append(e.options.jobOptions[:len(e.options.jobOptions):len(e.options.jobOptions)],
+			workerpool.WithDone(func(error) { s.finished(e) }))
+		if err := s.pool.Submit(ctx, e.job, opts...); err != nil {
+			s.finished(e)
+
+			s.mu.Lock()
+			snapshot := e.snapshot()
+			s.mu.Unlock()
+			s.config.OnError(snapshot, err)
+		}
+	}()
+}
+
+// finished records the end of a run and submits the next queued one
+func (s *Scheduler[T]) finished(e *entry[T]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e.running--
+	if e.queued > 0 && e.running == 0 {
+		e.queued--
+		s.submit(e)
+	}
+}
+
+func (e *entry[T]) jitter() time.Duration {
+	if e.options.jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(e.options.jitter)))
+}
+
+func (s *Scheduler[T]) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,230 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PCS-Indonesia/pakakeh/concurrency/workerpool"
+)
+
+// recorder is the pool handler of the tests, jobs named "blocking" wait for release
+type recorder struct {
+	mu      sync.Mutex
+	runs    []string
+	release chan struct{}
+}
+
+func (r *recorder) handler() workerpool.JobHandlerFunc[string] {
+	return func() workerpool.JobFunc[string] {
+		return func(job string) error {
+			r.mu.Lock()
+			r.runs = append(r.runs, job)
+			r.mu.Unlock()
+
+			if job == "blocking" {
+				<-r.release
+			}
+			return nil
+		}
+	}
+}
+
+func (r *recorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.runs)
+}
+
+func startScheduler(t *testing.T, config Config) (*Scheduler[string], *recorder, *FakeClock) {
+	done := make(chan struct{})
+	rec := &recorder{release: make(chan struct{})}
+
+	pool := workerpool.New(done, rec.handler(), func(c *workerpool.Config) error {
+		c.WorkerNum = 5
+		return nil
+	})
+	pool.Start()
+
+	jakarta, err := time.LoadLocation("Asia/Jakarta")
+	require.NoError(t, err)
+
+	clock := NewFakeClock(time.Date(2026, 10, 19, 22, 59, 0, 0, jakarta))
+	config.Clock = clock
+	config.Location = jakarta
+
+	s, err := New(pool, config)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- s.Run(ctx)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		assert.ErrorIs(t, <-stopped, context.Canceled)
+
+		select {
+		case <-rec.release:
+		default:
+			close(rec.release)
+		}
+		close(done)
+		require.Eventually(t, pool.Closed, time.Second, time.Millisecond)
+	})
+
+	return s, rec, clock
+}
+
+// waitNext waits until the scheduler moved entry id to next
+func waitNext(t *testing.T, s *Scheduler[string], id EntryID, next time.Time) {
+	require.Eventually(t, func() bool {
+		for _, entry := range s.Entries() {
+			if entry.ID == id {
+				return entry.Next.Equal(next)
+			}
+		}
+		return false
+	}, time.Second, time.Millisecond)
+}
+
+func TestSchedulerCron(t *testing.T) {
+	s, rec, clock := startScheduler(t, Config{})
+
+	id, err := s.Cron("0 23 * * *", "settlement")
+	require.NoError(t, err)
+
+	_, err = s.Cron("0 25 * * *", "settlement")
+	assert.Error(t, err)
+
+	today := time.Date(2026, 10, 19, 23, 0, 0, 0, clock.Now().Location())
+	entries := s.Entries()
+	require.Len(t, entries, 1)
+	assert.True(t, entries[0].Next.Equal(today))
+
+	clock.Advance(59 * time.Second)
+	assert.Never(t, func() bool { return rec.count() > 0 }, 20*time.Millisecond, time.Millisecond)
+
+	clock.Advance(time.Second)
+	waitNext(t, s, id, today.AddDate(0, 0, 1))
+	require.Eventually(t, func() bool { return rec.count() == 1 }, time.Second, time.Millisecond)
+
+	entries = s.Entries()
+	assert.True(t, entries[0].Prev.Equal(today))
+
+	assert.True(t, s.Remove(id))
+	assert.False(t, s.Remove(id))
+	assert.Empty(t, s.Entries())
+}
+
+func TestSchedulerOneOff(t *testing.T) {
+	s, rec, clock := startScheduler(t, Config{})
+
+	after := s.After(30*time.Second, "status-check")
+	s.At(clock.Now().Add(-time.Minute), "overdue")
+	require.Eventually(t, func() bool { return rec.count() == 1 }, time.Second, time.Millisecond)
+	assert.Len(t, s.Entries(), 1)
+
+	clock.Advance(29 * time.Second)
+	assert.Never(t, func() bool { return rec.count() > 1 }, 20*time.Millisecond, time.Millisecond)
+
+	clock.Advance(time.Second)
+	require.Eventually(t, func() bool { return rec.count() == 2 }, time.Second, time.Millisecond)
+	assert.Empty(t, s.Entries())
+	assert.False(t, s.Remove(after))
+
+	rec.mu.Lock()
+	assert.Equal(t, []string{"overdue", "status-check"}, rec.runs)
+	rec.mu.Unlock()
+}
+
+func TestSchedulerOverlap(t *testing.T) {
+	for _, tc := range []struct {
+		policy       OverlapPolicy
+		started      int // runs started while the first one is blocked
+		queued       int
+		afterRelease int
+	}{
+		{policy: OverlapAllow, started: 3, afterRelease: 3},
+		{policy: OverlapSkip, started: 1, afterRelease: 1},
+		{policy: OverlapQueue, started: 1, queued: 2, afterRelease: 3},
+	} {
+		t.Run(map[OverlapPolicy]string{OverlapAllow: "allow", OverlapSkip: "skip", OverlapQueue: "queue"}[tc.policy], func(t *testing.T) {
+			s, rec, clock := startScheduler(t, Config{Overlap: tc.policy})
+
+			id, err := s.Cron("* * * * *", "blocking")
+			require.NoError(t, err)
+
+			next := time.Date(2026, 10, 19, 23, 0, 0, 0, clock.Now().Location())
+			for i := 0; i < 3; i++ {
+				clock.Advance(time.Minute)
+				next = next.Add(time.Minute)
+				waitNext(t, s, id, next)
+			}
+
+			require.Eventually(t, func() bool { return rec.count() == tc.started }, time.Second, time.Millisecond)
+			entries := s.Entries()
+			assert.Equal(t, tc.started, entries[0].Running)
+			assert.Equal(t, tc.queued, entries[0].Queued)
+
+			close(rec.release)
+			require.Eventually(t, func() bool {
+				entries := s.Entries()
+				return rec.count() == tc.afterRelease && entries[0].Running == 0 && entries[0].Queued == 0
+			}, time.Second, time.Millisecond)
+		})
+	}
+}
+
+func TestSchedulerKeepsCallerDone(t *testing.T) {
+	s, rec, clock := startScheduler(t, Config{Overlap: OverlapQueue})
+
+	var callerDone atomic.Int32
+	id, err := s.Cron("* * * * *", "blocking", WithJobOptions(workerpool.WithDone(func(err error) {
+		assert.NoError(t, err)
+		callerDone.Add(1)
+	})))
+	require.NoError(t, err)
+
+	next := time.Date(2026, 10, 19, 23, 0, 0, 0, clock.Now().Location())
+	for i := 0; i < 2; i++ {
+		clock.Advance(time.Minute)
+		next = next.Add(time.Minute)
+		waitNext(t, s, id, next)
+	}
+
+	close(rec.release)
+	// the queued run is only submitted when the scheduler saw the first one finish
+	require.Eventually(t, func() bool {
+		entries := s.Entries()
+		return rec.count() == 2 && entries[0].Running == 0 && entries[0].Queued == 0
+	}, time.Second, time.Millisecond)
+	require.Eventually(t, func() bool { return callerDone.Load() == 2 }, time.Second, time.Millisecond)
+}
+
+func TestSchedulerJitter(t *testing.T) {
+	s, rec, clock := startScheduler(t, Config{Jitter: 10 * time.Second})
+
+	s.After(time.Minute, "reconciliation", WithJobOptions(workerpool.WithPriority(workerpool.PriorityHigh)))
+	s.After(time.Minute, "status-check", WithJitter(0))
+
+	// the reconciliation is delayed by up to 10 seconds
+	clock.Advance(time.Minute)
+	require.Eventually(t, func() bool { return rec.count() == 1 }, time.Second, time.Millisecond)
+	assert.Len(t, s.Entries(), 1)
+
+	rec.mu.Lock()
+	assert.Equal(t, "status-check", rec.runs[0])
+	rec.mu.Unlock()
+
+	clock.Advance(10 * time.Second)
+	require.Eventually(t, func() bool { return rec.count() == 2 }, time.Second, time.Millisecond)
+	assert.Empty(t, s.Entries())
+}
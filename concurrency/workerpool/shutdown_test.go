@@ -0,0 +1,161 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PCS-Indonesia/pakakeh/httpclient"
+)
+
+func TestWaitAndShutdown(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+
+	var sum atomic.Int32
+	handler := func() JobFunc[int] {
+		return func(n int) error {
+			time.Sleep(2 * time.Millisecond)
+			sum.Add(int32(n))
+			return nil
+		}
+	}
+
+	p := New(done, handler, func(c *Config) error {
+		c.WorkerNum = 2
+		return nil
+	})
+	p.Start()
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, p.Submit(context.Background(), 1))
+	}
+	p.Wait()
+	assert.Equal(t, int32(10), sum.Load())
+	assert.False(t, p.Closed())
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, p.Submit(context.Background(), 1))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	abandoned, err := p.Shutdown(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, abandoned)
+	assert.Equal(t, int32(20), sum.Load())
+	assert.True(t, p.Closed())
+	assert.ErrorIs(t, p.Submit(context.Background(), 1), ErrPoolClosed)
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+
+	release := make(chan struct{})
+	p := New(done, func() JobFunc[int] {
+		return func(int) error { return nil }
+	}, func(c *Config) error {
+		c.WorkerNum = 1
+		return nil
+	})
+	p.Start()
+
+	blocked := func(n int) (int, error) {
+		<-release
+		return n, nil
+	}
+
+	futures := make([]*Future[int], 4)
+	for i := range futures {
+		future, err := SubmitWithResult(context.Background(), p, i, blocked)
+		require.NoError(t, err)
+		futures[i] = future
+	}
+	require.Eventually(t, func() bool { return p.Stats().BusyWorkers == 1 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	abandoned, err := p.Shutdown(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 3, abandoned)
+
+	for _, future := range futures[1:] {
+		_, err := future.Get(context.Background())
+		assert.ErrorIs(t, err, ErrJobAbandoned)
+	}
+
+	// the running job is not interrupted
+	assert.False(t, p.Closed())
+	close(release)
+
+	value, err := futures[0].Get(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, value)
+	waitClosed(t, p)
+}
+
+func TestStop(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+
+	var runs atomic.Int32
+	release := make(chan struct{})
+	handler := func() JobFunc[int] {
+		return func(n int) error {
+			runs.Add(1)
+			if n == 0 {
+				<-release
+				return nil
+			}
+			return errors.New("partner down")
+		}
+	}
+
+	p := New(done, handler, func(c *Config) error {
+		c.WorkerNum = 2
+		c.MaxAttempts = 3
+		c.Backoff = httpclient.NewConstantBackoff(time.Hour, 0)
+		return nil
+	})
+	p.Start()
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, p.Submit(context.Background(), i))
+	}
+	// job 0 keeps a worker busy, the others wait for their retry
+	require.Eventually(t, func() bool { return runs.Load() == 4 && p.Stats().BusyWorkers == 1 }, time.Second, time.Millisecond)
+
+	assert.Equal(t, 3, p.Stop())
+	assert.ErrorIs(t, p.TrySubmit(4), ErrPoolClosed)
+
+	close(release)
+	p.Wait()
+	waitClosed(t, p)
+
+	assert.Equal(t, 0, p.Stop())
+}
+
+func TestShutdownBeforeStart(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+
+	p := New(done, countingHandler(new(atomic.Int32)))
+	require.NoError(t, p.TrySubmit(1))
+	require.NoError(t, p.TrySubmit(2))
+
+	abandoned, err := p.Shutdown(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, abandoned)
+	assert.True(t, p.Closed())
+
+	p.Start()
+	assert.Zero(t, p.GetSize())
+}
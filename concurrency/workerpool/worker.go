@@ -1,68 +1,92 @@
 package workerpool
 
 import (
+	"context"
 	"sync"
 )
 
-type WorkerImplementor interface {
-	Start(JobFunc)
+type WorkerImplementor[T any] interface {
+	Start(ContextJobFunc[T])
 	Closed() bool
 }
 
-type JobFunc func(Job) error
+// JobFunc processes a single job
+type JobFunc[T any] func(T) error
+
+// ContextJobFunc processes a single job. ctx is cancelled when the job times out, see
+// Config.JobTimeout, and when the pool stops without waiting for it.
+type ContextJobFunc[T any] func(ctx context.Context, job T) error
+
+// withoutContext adapts a JobFunc, it cannot be interrupted
+func withoutContext[T any](jobFunc JobFunc[T]) ContextJobFunc[T] {
+	return func(_ context.Context, job T) error {
+		return jobFunc(job)
+	}
+}
 
 // NewWorker creates a worker.
-func NewWorker(done <-chan struct{}, workerPool chan<- chan Job, wg *sync.WaitGroup,
-	jobPool <-chan struct{}, errors chan error) *Worker {
+func NewWorker[T any](done <-chan struct{}, workerPool chan<- chan task[T], wg *sync.WaitGroup,
+	executor *executor[T]) *Worker[T] {
 
-	bucket := make(chan Job, 1)
+	bucket := make(chan task[T], 1)
 
-	return &Worker{
-		pool:    workerPool,
-		jobPool: jobPool,
-		bucket:  bucket,
-		done:    done,
-		errors:  errors,
-		wg:      wg,
-		mu:      &sync.Mutex{},
+	return &Worker[T]{
+		pool:     workerPool,
+		bucket:   bucket,
+		done:     done,
+		executor: executor,
+		wg:       wg,
+		mu:       &sync.Mutex{},
 	}
 }
 
 // Start will pushes the worker into workerqueue, listens stop sinyal.
-func (w *Worker) Start(jobFunc JobFunc) {
+func (w *Worker[T]) Start(jobFunc ContextJobFunc[T]) {
 	go func() {
+		defer w.stop()
+
 		for {
+			// tell the dispatcher this worker is idle
 			select {
-			case <-w.jobPool:
-				// worker has received a job request
-				w.pool <- w.bucket
-				job := <-w.bucket
-				if err := jobFunc(job); err != nil {
-					logger.Printf("Error when process job -> %s \n", err.Error())
-					if w.errors != nil {
-						w.errors <- err
-					}
-				}
+			case w.pool <- w.bucket:
 			case <-w.done:
-				// worker has received stop sinyal
-				w.done = nil
-				close(w.bucket)
-				w.wg.Done()
+				return
+			}
 
-				w.mu.Lock()
-				if !w.closed {
-					w.closed = true
+			select {
+			case t := <-w.bucket:
+				// worker has received a job request
+				w.process(jobFunc, t)
+			case <-w.done:
+				// worker has received stop sinyal, the dispatcher may have sent a last job
+				select {
+				case t := <-w.bucket:
+					w.process(jobFunc, t)
+				default:
 				}
-				w.mu.Unlock()
-
 				return
 			}
 		}
 	}()
 }
 
+func (w *Worker[T]) process(jobFunc ContextJobFunc[T], t task[T]) {
+	w.executor.execute(jobFunc, t)
+}
+
+func (w *Worker[T]) stop() {
+	w.done = nil
+	w.wg.Done()
+
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+	}
+	w.mu.Unlock()
+}
+
 // Closed worker received a signal to stop
-func (w *Worker) Closed() bool {
+func (w *Worker[T]) Closed() bool {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 	return w.closed
@@ -1,23 +1,34 @@
 package workerpool
 
 import (
+	"context"
 	"log"
 	"os"
 	"sync"
+	"time"
 )
 
 // PoolImplementor is the Pool interface.
-type PoolImplementor interface {
+type PoolImplementor[T any] interface {
 	Start()
+	Submit(context.Context, T, ...JobOption) error
+	TrySubmit(T, ...JobOption) error
+	Wait()
+	Shutdown(context.Context) (int, error)
+	Stop() int
 	Closed() bool
 	GetSize() int
+	Stats() Stats
 	StopDispatch(...int)
 	SetMaxPoolNum(int)
 }
 
 type (
-	Option         = func(*Config) error
-	JobHandlerFunc = func() JobFunc
+	Option = func(*Config) error
+	// JobHandlerFunc creates the JobFunc of a dispatcher, it is called once per dispatcher
+	JobHandlerFunc[T any] func() JobFunc[T]
+	// ContextJobHandlerFunc creates the ContextJobFunc of a dispatcher, it is called once per dispatcher
+	ContextJobHandlerFunc[T any] func() ContextJobFunc[T]
 )
 
 var (
@@ -29,11 +40,30 @@ var (
 		MaxDispatcherNum:   3,
 		WorkerNum:          20,
 		JobQueueBufferSize: 1000,
+
+		ScaleInterval:        time.Second,
+		ScaleCoolDown:        30 * time.Second,
+		ScaleUpUtilization:   0.8,
+		ScaleDownUtilization: 0.3,
 	}
 )
 
-// New creates a pool.
-func New(done <-chan struct{}, jobHandlerFunc JobHandlerFunc, options ...Option) *Pool {
+// New creates a pool for jobs of type T.
+func New[T any](done <-chan struct{}, jobHandlerFunc JobHandlerFunc[T], options ...Option) *Pool[T] {
+	p := newPool[T](done, options)
+	p.JobHandlerFunc = jobHandlerFunc
+	return p
+}
+
+// NewWithContext creates a pool for jobs of type T whose JobFunc receives a context,
+// cancelled on timeout and when the pool stops without waiting for the job.
+func NewWithContext[T any](done <-chan struct{}, jobHandlerFunc ContextJobHandlerFunc[T], options ...Option) *Pool[T] {
+	p := newPool[T](done, options)
+	p.contextHandler = jobHandlerFunc
+	return p
+}
+
+func newPool[T any](done <-chan struct{}, options []Option) *Pool[T] {
 	pConfig := DefaultConfig
 	setOption(&pConfig, options...)
 
@@ -50,76 +80,183 @@ func New(done <-chan struct{}, jobHandlerFunc JobHandlerFunc, options ...Option)
 		logger.Panicln("WorkerNum must greater than 0")
 	}
 
-	p := &Pool{
-		JobQueue:       make(chan Job, pConfig.JobQueueBufferSize),
-		config:         pConfig,
-		JobHandlerFunc: jobHandlerFunc,
-		done:           done,
-		doneDispatcher: make(chan struct{}, pConfig.InitDispatcherNum),
-		mu:             &sync.Mutex{},
-		wg:             &sync.WaitGroup{},
+	p := &Pool[T]{
+		JobQueue:  make(chan T, pConfig.JobQueueBufferSize),
+		config:    pConfig,
+		done:      done,
+		draining:  make(chan struct{}),
+		stopped:   make(chan struct{}),
+		queue:     newJobQueue[task[T]](pConfig.JobQueueBufferSize, pConfig.PriorityAging),
+		forwarded: make(chan struct{}),
+		mu:        &sync.Mutex{},
+		wg:        &sync.WaitGroup{},
 	}
 
 	if pConfig.Errors {
 		p.Errors = make(chan error, 1)
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.executor = newExecutor(ctx, p.queue, pConfig, p.Errors)
+
 	return p
 }
 
-// Start run dispatchers in the pool.
-func (p *Pool) Start() {
-	dispatcherCounter := make(chan []struct{}, p.config.InitDispatcherNum)
+// Submit queues job, waiting for a free slot while the queue is full. It returns ctx.Err()
+// when ctx is done first, and ErrPoolClosed once the pool is closed.
+func (p *Pool[T]) Submit(ctx context.Context, job T, opts ...JobOption) error {
+	t := newTask(job, opts)
+	return p.queue.push(ctx, t, t.options.priority, true)
+}
+
+// TrySubmit queues job without waiting. It returns ErrQueueFull when the queue is full,
+// and ErrPoolClosed once the pool is closed.
+func (p *Pool[T]) TrySubmit(job T, opts ...JobOption) error {
+	t := newTask(job, opts)
+	return p.queue.push(context.Background(), t, t.options.priority, false)
+}
+
+// forward moves the jobs sent to the deprecated JobQueue channel into the queue until the
+// pool closes JobQueue. Start runs it, so a pool that is never started leaves no goroutine.
+func (p *Pool[T]) forward() {
+	defer close(p.forwarded)
 
-	for range dispatcherCounter {
-		p.newDispatcher()
+	for job := range p.JobQueue {
+		if err := p.queue.push(context.Background(), task[T]{job: job}, PriorityNormal, true); err != nil {
+			logger.Printf("Job from JobQueue dropped -> %s \n", err.Error())
+		}
 	}
+}
 
+// Start run dispatchers in the pool. It does nothing once the pool is closing.
+func (p *Pool[T]) Start() {
 	p.mu.Lock()
-	p.poolNum = p.config.InitDispatcherNum
+	if p.closed || p.stopping {
+		p.mu.Unlock()
+		return
+	}
+	for i := 0; i < p.config.InitDispatcherNum; i++ {
+		p.addDispatcher()
+	}
+	p.forwardOnce.Do(func() { go p.forward() })
 	p.mu.Unlock()
 
-	go p.listen() // listen for pool done signal concurrently
-}
-
-func (p *Pool) newDispatcher() {
-	j := p.JobHandlerFunc()
-	p.wg.Add(1)
-	d := NewDispatcher(p.doneDispatcher, p.wg, p.config.WorkerNum,
-		p.JobQueue, j, p.Errors)
-	d.Dispatch()
+	go p.listen()    // listen for pool done signal concurrently
+	go p.supervise() // scale the dispatchers between InitDispatcherNum and MaxDispatcherNum
 }
 
-func (p *Pool) listen() {
+func (p *Pool[T]) listen() {
 	for { // loop until pool is closed or done
 		select {
 		case _, open := <-p.done:
 			if !open {
-				close(p.JobQueue)
-				p.wg.Wait()
+				p.close()
+				return
+			}
+		case <-p.stopped:
+			return
+		}
+	}
+}
 
-				close(p.doneDispatcher)
-				p.mu.Lock()
+// close stops intake and closes the pool once the queued jobs are processed. It does not wait.
+func (p *Pool[T]) close() {
+	p.stopOnce.Do(func() {
+		go p.drain()
+	})
+}
 
-				if !p.closed {
-					p.closed = true
-				}
+func (p *Pool[T]) drain() {
+	// stop intake, the queued jobs are still processed
+	close(p.JobQueue)
+	dropped := 0
+	p.forwardOnce.Do(func() {
+		// never started, the jobs sent to JobQueue are abandoned like the queued ones
+		for range p.JobQueue {
+			dropped++
+		}
+		close(p.forwarded)
+	})
+	<-p.forwarded
+	p.queue.close()
 
-				p.poolNum = 0
-				p.mu.Unlock()
+	// no dispatcher is added once draining, so wg.Add never races with wg.Wait
+	p.mu.Lock()
+	p.stopping = true
+	close(p.draining)
+	p.mu.Unlock()
 
-				if p.config.Errors {
-					close(p.Errors)
-				}
+	p.wg.Wait()
+	p.cancel()
 
-				return
-			}
-		}
+	// anything left was queued on a pool that was never started
+	abandoned := p.queue.abort(p.abandon) + dropped
+
+	p.mu.Lock()
+
+	if !p.closed {
+		p.closed = true
+	}
+
+	p.poolNum = 0
+	p.dispatchers = nil
+	p.abandoned = abandoned
+	p.mu.Unlock()
+
+	if p.config.Errors {
+		close(p.Errors)
+	}
+
+	close(p.stopped)
+}
+
+// abandon resolves the future of a job dropped by Shutdown or Stop
+func (p *Pool[T]) abandon(t task[T]) {
+	if t.result != nil {
+		t.result(&JobError[T]{Job: t.job, Attempts: t.attempt, Err: ErrJobAbandoned})
+	}
+}
+
+// Wait blocks until every accepted job finished, including the retries. It does not close
+// the pool, and must not be called before Start while jobs are queued.
+func (p *Pool[T]) Wait() {
+	p.queue.wait()
+}
+
+// Shutdown stops intake and waits for the running, queued and retrying jobs to finish,
+// then closes the pool like closing the done channel does. When ctx is done first, the
+// jobs not started yet and the pending retries are abandoned, and Shutdown returns their
+// number with ctx.Err(). The context of the running jobs is cancelled and they are no
+// longer retried. Abandoned jobs resolve their future with ErrJobAbandoned.
+func (p *Pool[T]) Shutdown(ctx context.Context) (int, error) {
+	p.close()
+
+	select {
+	case <-p.stopped:
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return p.abandoned, nil
+	case <-ctx.Done():
+		abandoned := p.queue.abort(p.abandon)
+		p.cancel()
+		return abandoned, ctx.Err()
 	}
 }
 
-// SetMaxPoolNum applies MaxPoolNum to Pool Config.
-func (p *Pool) SetMaxPoolNum(maxPoolNum int) {
+// Stop closes the pool right away, abandoning the queued jobs and the pending retries and
+// cancelling the running jobs like Shutdown does at its deadline, and returns the number
+// of abandoned jobs. It does not wait for the running jobs, use Wait for that.
+func (p *Pool[T]) Stop() int {
+	p.close()
+	abandoned := p.queue.abort(p.abandon)
+	p.cancel()
+	return abandoned
+}
+
+// SetMaxPoolNum applies MaxPoolNum to Pool Config. The supervisor scales up to the new
+// maximum when needed, or retires the dispatchers above it.
+func (p *Pool[T]) SetMaxPoolNum(maxPoolNum int) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	opt := func(c *Config) error {
@@ -129,28 +266,28 @@ func (p *Pool) SetMaxPoolNum(maxPoolNum int) {
 	setOption(&p.config, opt)
 }
 
-// StopDispatch signals dispatcher to stop
-func (p *Pool) StopDispatch(num ...int) {
-	n := Min(1, p.poolNum)
-	if len(num) > 0 && num[0] > 1 {
-		n = Min(num[0], p.poolNum)
-	}
-
+// StopDispatch signals dispatcher to stop, default is 1. At least one dispatcher is kept.
+// The supervisor may start dispatchers again when the load requires it.
+func (p *Pool[T]) StopDispatch(num ...int) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	if !p.closed && p.poolNum-n > 0 {
-		poolCounter := make(chan []struct{}, n)
+	n := 1
+	if len(num) > 0 && num[0] > 1 {
+		n = num[0]
+	}
+	n = Min(n, p.poolNum-1)
 
-		for range poolCounter {
-			p.doneDispatcher <- struct{}{}
-			p.poolNum--
-			p.wg.Done()
-		}
+	if p.closed || p.stopping {
+		return
+	}
+
+	for i := 0; i < n; i++ {
+		p.retireDispatcher()
 	}
 }
 
-func (p *Pool) Closed() bool {
+func (p *Pool[T]) Closed() bool {
 	p.mu.Lock()
 	// p.closed = false
 	defer p.mu.Unlock()
@@ -159,7 +296,7 @@ func (p *Pool) Closed() bool {
 }
 
 // GetSize returns current number of dispatcher.
-func (p *Pool) GetSize() int {
+func (p *Pool[T]) GetSize() int {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	return p.poolNum
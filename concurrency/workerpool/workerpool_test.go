@@ -0,0 +1,130 @@
+package workerpool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func countingHandler(count *atomic.Int32) JobHandlerFunc[int] {
+	return func() JobFunc[int] {
+		return func(n int) error {
+			count.Add(int32(n))
+			return nil
+		}
+	}
+}
+
+func waitClosed[T any](t *testing.T, p *Pool[T]) {
+	require.Eventually(t, p.Closed, time.Second, time.Millisecond)
+}
+
+func TestSubmitBackpressure(t *testing.T) {
+	done := make(chan struct{})
+	var sum atomic.Int32
+
+	p := New(done, countingHandler(&sum), func(c *Config) error {
+		c.WorkerNum = 2
+		c.JobQueueBufferSize = 2
+		return nil
+	})
+
+	// the pool is not started yet, so the queue fills up
+	require.NoError(t, p.TrySubmit(1))
+	require.NoError(t, p.Submit(context.Background(), 2))
+	assert.ErrorIs(t, p.TrySubmit(4), ErrQueueFull)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, p.Submit(ctx, 4), context.DeadlineExceeded)
+
+	p.Start()
+	require.NoError(t, p.Submit(context.Background(), 4))
+	p.JobQueue <- 8
+
+	close(done)
+	waitClosed(t, p)
+
+	assert.Equal(t, int32(15), sum.Load())
+	assert.ErrorIs(t, p.Submit(context.Background(), 16), ErrPoolClosed)
+	assert.ErrorIs(t, p.TrySubmit(16), ErrPoolClosed)
+}
+
+func TestSubmitUnblockedByClose(t *testing.T) {
+	done := make(chan struct{})
+	var sum atomic.Int32
+
+	p := New(done, countingHandler(&sum), func(c *Config) error {
+		c.JobQueueBufferSize = 1
+		return nil
+	})
+	require.NoError(t, p.TrySubmit(1))
+
+	submitted := make(chan error, 1)
+	go func() {
+		submitted <- p.Submit(context.Background(), 2)
+	}()
+
+	p.Start()
+	close(done)
+
+	select {
+	case err := <-submitted:
+		// the blocked producer either got the free slot or was told the pool is closed
+		if err != nil {
+			assert.ErrorIs(t, err, ErrPoolClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Submit still blocked after the pool was closed")
+	}
+	waitClosed(t, p)
+}
+
+func TestLegacyJobPool(t *testing.T) {
+	done := make(chan struct{})
+	var sum atomic.Int32
+
+	handler := func() JobFunc[Job] {
+		return func(j Job) error {
+			sum.Add(int32(j.Data.(int)))
+			return nil
+		}
+	}
+
+	p := New(done, handler)
+	p.Start()
+
+	require.NoError(t, p.Submit(context.Background(), Job{Data: 1}))
+	p.JobQueue <- Job{Data: 2}
+
+	close(done)
+	waitClosed(t, p)
+
+	assert.Equal(t, int32(3), sum.Load())
+}
+
+func TestLegacyJobQueueBeforeStart(t *testing.T) {
+	done := make(chan struct{})
+	var sum atomic.Int32
+
+	p := New(done, countingHandler(&sum))
+	p.JobQueue <- 1 // buffered until Start runs the forwarding
+	p.JobQueue <- 2
+
+	p.Start()
+	close(done)
+	waitClosed(t, p)
+	assert.Equal(t, int32(3), sum.Load())
+
+	// a pool closed without Start abandons what was sent to JobQueue
+	unstarted := New(make(chan struct{}), countingHandler(&sum))
+	unstarted.JobQueue <- 4
+	abandoned, err := unstarted.Shutdown(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, abandoned)
+	assert.Equal(t, int32(3), sum.Load())
+}
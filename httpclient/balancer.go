@@ -0,0 +1,318 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Strategy defines how the balancer selects an endpoint for each attempt
+type Strategy int
+
+const (
+	// StrategyRoundRobin rotates through healthy endpoints
+	StrategyRoundRobin Strategy = iota
+	// StrategyWeighted distributes attempts proportionally to Endpoint.Weight
+	StrategyWeighted
+	// StrategyFailover always uses the first healthy endpoint, e.g. primary then DR
+	StrategyFailover
+)
+
+const (
+	defaultFailureThreshold   = 3
+	defaultCoolDown           = 30 * time.Second
+	defaultHealthCheckTimeout = 5 * time.Second
+)
+
+// Endpoint is a base URL the balancer can send requests to
+type Endpoint struct {
+	URL    string
+	Weight int // only used by StrategyWeighted, defaults to 1
+}
+
+// BalancerConfig configures endpoint selection and ejection
+type BalancerConfig struct {
+	Strategy Strategy
+	// FailureThreshold is the number of consecutive failures (transport errors or 5xx)
+	// before an endpoint is ejected. Default is 3.
+	FailureThreshold int
+	// CoolDown is how long an ejected endpoint stays out before it is tried again. Default is 30s.
+	CoolDown time.Duration
+	// HealthCheckPath and HealthCheckInterval enable active health checks of ejected
+	// endpoints. A 2xx response brings the endpoint back before the cool-down ends.
+	HealthCheckPath     string
+	HealthCheckInterval time.Duration
+	HealthCheckTimeout  time.Duration
+	// HealthCheckClient sends the health check requests, defaults to an *http.Client
+	// with HealthCheckTimeout.
+	HealthCheckClient DoReq
+}
+
+// EndpointHealth is a snapshot of an endpoint state
+type EndpointHealth struct {
+	URL                 string
+	Healthy             bool
+	ConsecutiveFailures int
+	TotalRequests       int64
+	TotalFailures       int64
+	EjectedAt           time.Time
+	LastError           string
+}
+
+// Balancer selects an endpoint for every attempt made by CustomHttpClient and tracks their health
+type Balancer struct {
+	config    BalancerConfig
+	endpoints []*endpointState
+	cursor    int
+	mu        sync.Mutex
+	done      chan struct{}
+	closeOnce sync.Once
+	now       func() time.Time
+}
+
+type endpointState struct {
+	url           *url.URL
+	weight        int
+	currentWeight int
+	failures      int
+	ejected       bool
+	ejectedAt     time.Time
+	total         int64
+	totalFailures int64
+	lastError     string
+}
+
+// NewBalancer returns a balancer for the given endpoints. Health checks, if configured,
+// run in the background until Close is called.
+func NewBalancer(config BalancerConfig, endpoints ...Endpoint) (*Balancer, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("balancer needs at least one endpoint")
+	}
+
+	if config.FailureThreshold < 1 {
+		config.FailureThreshold = defaultFailureThreshold
+	}
+	if config.CoolDown <= 0 {
+		config.CoolDown = defaultCoolDown
+	}
+	if config.HealthCheckTimeout <= 0 {
+		config.HealthCheckTimeout = defaultHealthCheckTimeout
+	}
+	if config.HealthCheckClient == nil {
+		config.HealthCheckClient = &http.Client{Timeout: config.HealthCheckTimeout}
+	}
+
+	b := &Balancer{
+		config: config,
+		done:   make(chan struct{}),
+		now:    time.Now,
+	}
+
+	for _, endpoint := range endpoints {
+		parsed, err := url.Parse(endpoint.URL)
+		if err != nil || parsed.Host == "" {
+			return nil, errors.Errorf("invalid endpoint URL %q", endpoint.URL)
+		}
+
+		weight := endpoint.Weight
+		if weight < 1 {
+			weight = 1
+		}
+
+		b.endpoints = append(b.endpoints, &endpointState{url: parsed, weight: weight})
+	}
+
+	if config.HealthCheckPath != "" && config.HealthCheckInterval > 0 {
+		go b.healthCheckLoop()
+	}
+
+	return b, nil
+}
+
+// Close stops the background health checks
+func (b *Balancer) Close() {
+	b.closeOnce.Do(func() {
+		close(b.done)
+	})
+}
+
+// Health returns the current state of every endpoint, in the configured order
+func (b *Balancer) Health() []EndpointHealth {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	health := make([]EndpointHealth, len(b.endpoints))
+	for i, ep := range b.endpoints {
+		health[i] = EndpointHealth{
+			URL:                 ep.url.String(),
+			Healthy:             !ep.ejected,
+			ConsecutiveFailures: ep.failures,
+			TotalRequests:       ep.total,
+			TotalFailures:       ep.totalFailures,
+			EjectedAt:           ep.ejectedAt,
+			LastError:           ep.lastError,
+		}
+	}
+
+	return health
+}
+
+// pick selects an endpoint, preferring healthy ones that were not tried yet in this call.
+// When every endpoint is ejected it fails open and picks among all of them.
+func (b *Balancer) pick(tried map[*endpointState]bool) *endpointState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	var available, untried []*endpointState
+	for _, ep := range b.endpoints {
+		if ep.ejected && now.Sub(ep.ejectedAt) >= b.config.CoolDown {
+			// half-open: one more failure ejects it again
+			ep.ejected = false
+			ep.failures = b.config.FailureThreshold - 1
+		}
+		if ep.ejected {
+			continue
+		}
+
+		available = append(available, ep)
+		if !tried[ep] {
+			untried = append(untried, ep)
+		}
+	}
+
+	candidates := untried
+	if len(candidates) == 0 {
+		candidates = available
+	}
+	if len(candidates) == 0 {
+		candidates = b.endpoints
+	}
+
+	switch b.config.Strategy {
+	case StrategyWeighted:
+		return b.pickWeighted(candidates)
+	case StrategyFailover:
+		return candidates[0]
+	default:
+		return b.pickRoundRobin(candidates)
+	}
+}
+
+func (b *Balancer) pickRoundRobin(candidates []*endpointState) *endpointState {
+	for i := 0; i < len(b.endpoints); i++ {
+		ep := b.endpoints[(b.cursor+i)%len(b.endpoints)]
+		for _, candidate := range candidates {
+			if candidate == ep {
+				b.cursor = (b.cursor + i + 1) % len(b.endpoints)
+				return ep
+			}
+		}
+	}
+
+	return candidates[0]
+}
+
+// pickWeighted implements smooth weighted round-robin
+func (b *Balancer) pickWeighted(candidates []*endpointState) *endpointState {
+	var best *endpointState
+	totalWeight := 0
+	for _, ep := range candidates {
+		ep.currentWeight += ep.weight
+		totalWeight += ep.weight
+		if best == nil || ep.currentWeight > best.currentWeight {
+			best = ep
+		}
+	}
+
+	best.currentWeight -= totalWeight
+	return best
+}
+
+// report records the outcome of an attempt against ep
+func (b *Balancer) report(ep *endpointState, failure error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ep.total++
+	if failure == nil {
+		ep.failures = 0
+		ep.ejected = false
+		return
+	}
+
+	ep.totalFailures++
+	ep.failures++
+	ep.lastError = failure.Error()
+	if !ep.ejected && ep.failures >= b.config.FailureThreshold {
+		ep.ejected = true
+		ep.ejectedAt = b.now()
+	}
+}
+
+// resolve rewrites target so that it points to ep, appending the request path to the endpoint path
+func (ep *endpointState) resolve(target *url.URL) *url.URL {
+	return joinURL(ep.url, target)
+}
+
+func (b *Balancer) healthCheckLoop() {
+	ticker := time.NewTicker(b.config.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			b.checkEjected()
+		case <-b.done:
+			return
+		}
+	}
+}
+
+// checkEjected probes every ejected endpoint and re-admits the ones that respond with 2xx
+func (b *Balancer) checkEjected() {
+	b.mu.Lock()
+	var ejected []*endpointState
+	for _, ep := range b.endpoints {
+		if ep.ejected {
+			ejected = append(ejected, ep)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, ep := range ejected {
+		if b.probe(ep) {
+			b.mu.Lock()
+			ep.ejected = false
+			ep.failures = 0
+			b.mu.Unlock()
+		}
+	}
+}
+
+func (b *Balancer) probe(ep *endpointState) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), b.config.HealthCheckTimeout)
+	defer cancel()
+
+	ref, err := url.Parse(b.config.HealthCheckPath)
+	if err != nil {
+		return false
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, ep.resolve(ref).String(), nil)
+	if err != nil {
+		return false
+	}
+
+	response, err := b.config.HealthCheckClient.Do(request)
+	if err != nil {
+		return false
+	}
+	defer drainAndClose(response.Body)
+
+	return response.StatusCode >= http.StatusOK && response.StatusCode < http.StatusMultipleChoices
+}
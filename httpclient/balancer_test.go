@@ -0,0 +1,140 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func countingServer(status *atomic.Int32, count *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count.Add(1)
+		w.WriteHeader(int(status.Load()))
+	}))
+}
+
+func TestBalancerRoundRobin(t *testing.T) {
+	balancer, err := NewBalancer(BalancerConfig{}, Endpoint{URL: "http://a"}, Endpoint{URL: "http://b"}, Endpoint{URL: "http://c"})
+	require.NoError(t, err)
+
+	var picked []string
+	for i := 0; i < 6; i++ {
+		picked = append(picked, balancer.pick(nil).url.Host)
+	}
+
+	assert.Equal(t, []string{"a", "b", "c", "a", "b", "c"}, picked)
+}
+
+func TestBalancerWeighted(t *testing.T) {
+	balancer, err := NewBalancer(BalancerConfig{Strategy: StrategyWeighted},
+		Endpoint{URL: "http://a", Weight: 3}, Endpoint{URL: "http://b", Weight: 1})
+	require.NoError(t, err)
+
+	counts := map[string]int{}
+	for i := 0; i < 8; i++ {
+		counts[balancer.pick(nil).url.Host]++
+	}
+
+	assert.Equal(t, map[string]int{"a": 6, "b": 2}, counts)
+}
+
+func TestBalancerResolveKeepsEncoding(t *testing.T) {
+	balancer, err := NewBalancer(BalancerConfig{}, Endpoint{URL: "http://dr.partner.com/v1/"})
+	require.NoError(t, err)
+
+	target, err := url.Parse("http://primary.partner.com/accounts/a%2Fb?z=1&a=2")
+	require.NoError(t, err)
+
+	resolved := balancer.pick(nil).resolve(target)
+	assert.Equal(t, "http://dr.partner.com/v1/accounts/a%2Fb?z=1&a=2", resolved.String())
+	assert.Equal(t, "/v1/accounts/a%2Fb?z=1&a=2", resolved.RequestURI())
+}
+
+func TestBalancerInvalidEndpoint(t *testing.T) {
+	_, err := NewBalancer(BalancerConfig{})
+	require.Error(t, err)
+
+	_, err = NewBalancer(BalancerConfig{}, Endpoint{URL: "/no-host"})
+	require.Error(t, err)
+}
+
+func TestHTTPClientFailoverToDR(t *testing.T) {
+	var primaryStatus, drStatus, primaryCount, drCount atomic.Int32
+	primaryStatus.Store(http.StatusServiceUnavailable)
+	drStatus.Store(http.StatusOK)
+
+	primary := countingServer(&primaryStatus, &primaryCount)
+	defer primary.Close()
+	dr := countingServer(&drStatus, &drCount)
+	defer dr.Close()
+
+	balancer, err := NewBalancer(BalancerConfig{Strategy: StrategyFailover, FailureThreshold: 2, CoolDown: time.Minute},
+		Endpoint{URL: primary.URL}, Endpoint{URL: dr.URL})
+	require.NoError(t, err)
+
+	now := time.Now()
+	balancer.now = func() time.Time { return now }
+
+	client := NewClient(WithTimeout(10*time.Millisecond), WithRetryCount(1), WithBalancer(balancer))
+
+	// first call: primary fails, the retry goes to DR
+	response, err := client.Get("/status", http.Header{})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, int32(1), primaryCount.Load())
+	assert.Equal(t, int32(1), drCount.Load())
+
+	// second call: primary reaches the threshold and gets ejected
+	_, err = client.Get("/status", http.Header{})
+	require.NoError(t, err)
+
+	health := client.EndpointHealth()
+	require.Len(t, health, 2)
+	assert.False(t, health[0].Healthy)
+	assert.Equal(t, 2, health[0].ConsecutiveFailures)
+	assert.True(t, health[1].Healthy)
+
+	// while ejected, primary is skipped
+	_, err = client.Get("/status", http.Header{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), primaryCount.Load())
+
+	// after the cool-down primary is tried again and recovers
+	primaryStatus.Store(http.StatusOK)
+	now = now.Add(time.Minute)
+
+	_, err = client.Get("/status", http.Header{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), primaryCount.Load())
+	assert.True(t, client.EndpointHealth()[0].Healthy)
+}
+
+func TestBalancerHealthCheckReadmitsEndpoint(t *testing.T) {
+	var status, count atomic.Int32
+	status.Store(http.StatusOK)
+
+	server := countingServer(&status, &count)
+	defer server.Close()
+
+	balancer, err := NewBalancer(BalancerConfig{
+		FailureThreshold:    1,
+		CoolDown:            time.Hour,
+		HealthCheckPath:     "/health",
+		HealthCheckInterval: 5 * time.Millisecond,
+	}, Endpoint{URL: server.URL})
+	require.NoError(t, err)
+	defer balancer.Close()
+
+	balancer.report(balancer.endpoints[0], assert.AnError)
+	require.False(t, balancer.Health()[0].Healthy)
+
+	assert.Eventually(t, func() bool {
+		return balancer.Health()[0].Healthy
+	}, time.Second, 5*time.Millisecond)
+}
@@ -0,0 +1,173 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const defaultBatchConcurrency = 10
+
+// ErrBatchAborted is set on the results of requests that were never sent because the batch
+// stopped early, either in fail-fast mode or because the context was cancelled
+var ErrBatchAborted = errors.New("batch aborted before the request was sent")
+
+// BatchMode defines what happens when a request of the batch fails
+type BatchMode int
+
+const (
+	// BatchCollectAll sends every request and collects all results
+	BatchCollectAll BatchMode = iota
+	// BatchFailFast stops sending new requests after the first failure. Requests already in
+	// flight are completed so their responses stay readable.
+	BatchFailFast
+)
+
+// BatchConfig configures BatchDo
+type BatchConfig struct {
+	// Concurrency is the maximum number of requests in flight, default is 10
+	Concurrency int
+	// RateLimit is the maximum number of requests started per second, 0 means unlimited
+	RateLimit float64
+	// Burst is the number of requests that can start at once under RateLimit, default is 1
+	Burst int
+	Mode  BatchMode
+	// IsFailure decides whether a result is a failure for BatchFailFast. Default is a
+	// transport error or a 5xx status, the same as the retry mechanism.
+	IsFailure func(response *http.Response, err error) bool
+	// OnProgress is called after every request completes, from the request goroutine
+	OnProgress func(completed, total int, result BatchResult)
+}
+
+// BatchResult is the outcome of a single request of the batch
+type BatchResult struct {
+	Index    int // position of the request in the input slice
+	Response *http.Response
+	Err      error
+	Duration time.Duration
+}
+
+// BatchDo sends requests through Do with bounded concurrency and an optional rate limit.
+// Results are returned in input order, and every response body must be closed by the caller.
+// The returned error is the first failure in BatchFailFast mode, or the context error when
+// ctx was cancelled before every request was sent. Every request is also cancelled with ctx,
+// which aborts the requests in flight and the reading of their bodies.
+func (c *CustomHttpClient) BatchDo(ctx context.Context, requests []*http.Request, config BatchConfig) ([]BatchResult, error) {
+	if config.Concurrency < 1 {
+		config.Concurrency = defaultBatchConcurrency
+	}
+	if config.IsFailure == nil {
+		config.IsFailure = func(response *http.Response, err error) bool {
+			return attemptFailure(response, err) != nil
+		}
+	}
+
+	var limiter *RateLimiter
+	if config.RateLimit > 0 {
+		limiter = NewRateLimiter(config.RateLimit, config.Burst)
+	}
+
+	results := make([]BatchResult, len(requests))
+	for i := range results {
+		results[i] = BatchResult{Index: i, Err: ErrBatchAborted}
+	}
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		completed int
+		firstErr  error
+	)
+
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	slots := make(chan struct{}, config.Concurrency)
+
+	stopped := func() bool {
+		select {
+		case <-stop:
+			return true
+		default:
+			return false
+		}
+	}
+
+dispatch:
+	for i, request := range requests {
+		select {
+		case slots <- struct{}{}:
+		case <-stop:
+			break dispatch
+		case <-ctx.Done():
+			break dispatch
+		}
+
+		if limiter != nil {
+			if err := limiter.Wait(ctx); err != nil {
+				<-slots
+				break dispatch
+			}
+		}
+
+		// the select above picks at random between ready cases, so check again before sending
+		if stopped() || ctx.Err() != nil {
+			<-slots
+			break dispatch
+		}
+
+		wg.Add(1)
+		go func(i int, request *http.Request) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			start := time.Now()
+			response, err := c.Do(request)
+			result := BatchResult{Index: i, Response: response, Err: err, Duration: time.Since(start)}
+
+			mu.Lock()
+			results[i] = result
+			completed++
+			done := completed
+			if config.Mode == BatchFailFast && firstErr == nil && config.IsFailure(response, err) {
+				firstErr = attemptFailure(response, err)
+				if firstErr == nil {
+					firstErr = errors.Errorf("batch request %d failed", i)
+				}
+				stopOnce.Do(func() { close(stop) })
+			}
+			mu.Unlock()
+
+			if config.OnProgress != nil {
+				config.OnProgress(done, len(requests), result)
+			}
+		}(i, withBatchContext(ctx, request))
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return results, firstErr
+	}
+
+	if completed < len(requests) {
+		return results, ctx.Err()
+	}
+
+	return results, nil
+}
+
+// withBatchContext returns request cancelled when either its own context or ctx is done. The
+// request context is not cancelled when the request completes, its body may still be read.
+func withBatchContext(ctx context.Context, request *http.Request) *http.Request {
+	if ctx.Done() == nil || request.Context() == ctx {
+		return request
+	}
+
+	requestCtx, cancel := context.WithCancelCause(request.Context())
+	context.AfterFunc(ctx, func() { cancel(context.Cause(ctx)) })
+
+	return request.WithContext(requestCtx)
+}
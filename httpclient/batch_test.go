@@ -0,0 +1,167 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func batchRequests(t *testing.T, baseURL string, n int) []*http.Request {
+	requests := make([]*http.Request, n)
+	for i := range requests {
+		request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/accounts/%d", baseURL, i), nil)
+		require.NoError(t, err)
+		requests[i] = request
+	}
+	return requests
+}
+
+func TestBatchDoCollectAllInOrder(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+
+	dummyHandler := func(w http.ResponseWriter, r *http.Request) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			seen := maxInFlight.Load()
+			if current <= seen || maxInFlight.CompareAndSwap(seen, current) {
+				break
+			}
+		}
+
+		id, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/accounts/"))
+		time.Sleep(time.Duration(10-id) * time.Millisecond)
+		if id == 3 {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		w.Write([]byte(strconv.Itoa(id)))
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(dummyHandler))
+	defer server.Close()
+
+	client := NewClient(WithTimeout(time.Second))
+
+	mu := &sync.Mutex{}
+	var progress []int
+	results, err := client.BatchDo(context.Background(), batchRequests(t, server.URL, 10), BatchConfig{
+		Concurrency: 3,
+		OnProgress: func(completed, total int, result BatchResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, 10, total)
+			progress = append(progress, completed)
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 10)
+
+	for i, result := range results {
+		require.NoError(t, result.Err)
+		assert.Equal(t, i, result.Index)
+		assert.Equal(t, strconv.Itoa(i), mockRespBody(t, result.Response))
+	}
+	assert.Equal(t, http.StatusNotFound, results[3].Response.StatusCode)
+	assert.LessOrEqual(t, maxInFlight.Load(), int32(3))
+	assert.Len(t, progress, 10)
+}
+
+func TestBatchDoFailFast(t *testing.T) {
+	var calls atomic.Int32
+
+	dummyHandler := func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path == "/accounts/1" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(dummyHandler))
+	defer server.Close()
+
+	client := NewClient(WithTimeout(time.Second))
+
+	results, err := client.BatchDo(context.Background(), batchRequests(t, server.URL, 20), BatchConfig{
+		Concurrency: 1,
+		Mode:        BatchFailFast,
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "status code 500")
+	assert.Equal(t, int32(2), calls.Load())
+
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, http.StatusInternalServerError, results[1].Response.StatusCode)
+	assert.ErrorIs(t, results[2].Err, ErrBatchAborted)
+}
+
+func TestBatchDoRateLimitAndCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithTimeout(time.Second))
+
+	start := time.Now()
+	_, err := client.BatchDo(context.Background(), batchRequests(t, server.URL, 5), BatchConfig{
+		Concurrency: 5,
+		RateLimit:   100,
+		Burst:       1,
+	})
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 35*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 25*time.Millisecond)
+	defer cancel()
+
+	results, err := client.BatchDo(ctx, batchRequests(t, server.URL, 50), BatchConfig{RateLimit: 100})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorIs(t, results[49].Err, ErrBatchAborted)
+}
+
+func TestBatchDoCancelledContext(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path == "/accounts/0" {
+			<-r.Context().Done() // the partner hangs
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithTimeout(time.Second))
+
+	// nothing is sent once ctx is done, even with free slots
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, err := client.BatchDo(ctx, batchRequests(t, server.URL, 20), BatchConfig{Concurrency: 20})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int32(0), calls.Load())
+	for _, result := range results {
+		assert.ErrorIs(t, result.Err, ErrBatchAborted)
+	}
+
+	// a request in flight is cancelled with ctx
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	start := time.Now()
+	results, err = client.BatchDo(ctx, batchRequests(t, server.URL, 1), BatchConfig{})
+	require.NoError(t, err, "every request was sent")
+	require.Error(t, results[0].Err)
+	assert.Contains(t, results[0].Err.Error(), context.Canceled.Error())
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
@@ -0,0 +1,64 @@
+package httpclient
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxDrainBytes bounds how much of a discarded body is read so the connection can be reused.
+// Larger bodies are just closed, which is cheaper than reading them.
+const maxDrainBytes = 64 << 10
+
+// drainAndClose reads what is left of body, up to maxDrainBytes, and closes it so the
+// underlying connection goes back to the pool
+func drainAndClose(body io.ReadCloser) {
+	if body == nil {
+		return
+	}
+
+	_, _ = io.CopyN(io.Discard, body, maxDrainBytes)
+	_ = body.Close()
+}
+
+// limitResponseBody fails right away when the announced Content-Length is above limit,
+// otherwise it wraps the body so that reading past limit fails with ErrResponseTooLarge
+func limitResponseBody(response *http.Response, limit int64) error {
+	if response.ContentLength > limit {
+		_ = response.Body.Close()
+		return fmt.Errorf("%w: content length %d exceeds limit of %d bytes", ErrResponseTooLarge, response.ContentLength, limit)
+	}
+
+	response.Body = &limitedBody{body: response.Body, limit: limit, remaining: limit}
+	return nil
+}
+
+// limitedBody returns ErrResponseTooLarge once more than limit bytes are read
+type limitedBody struct {
+	body      io.ReadCloser
+	limit     int64
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, fmt.Errorf("%w: exceeds limit of %d bytes", ErrResponseTooLarge, b.limit)
+	}
+
+	// read one byte past the limit to tell a body of exactly limit bytes from a larger one
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+
+	n, err := b.body.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n + int(b.remaining), fmt.Errorf("%w: exceeds limit of %d bytes", ErrResponseTooLarge, b.limit)
+	}
+
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.body.Close()
+}
@@ -0,0 +1,95 @@
+package httpclient
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPClientMaxResponseBodySize(t *testing.T) {
+	dummyHandler := func(w http.ResponseWriter, r *http.Request) {
+		body := strings.Repeat("a", 100)
+		if r.URL.Path == "/chunked" {
+			// no Content-Length, the limit is enforced while reading
+			w.(http.Flusher).Flush()
+		}
+		w.Write([]byte(body))
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(dummyHandler))
+	defer server.Close()
+
+	client := NewClient(WithTimeout(time.Second), WithMaxResponseBodySize(50))
+
+	response, err := client.Get(server.URL+"/sized", http.Header{})
+	assert.True(t, errors.Is(err, ErrResponseTooLarge))
+	assert.Nil(t, response)
+
+	response, err = client.Get(server.URL+"/chunked", http.Header{})
+	require.NoError(t, err)
+
+	body, err := io.ReadAll(response.Body)
+	assert.True(t, errors.Is(err, ErrResponseTooLarge))
+	assert.Len(t, body, 50)
+	response.Body.Close()
+
+	client = NewClient(WithTimeout(time.Second), WithMaxResponseBodySize(100))
+	response, err = client.Get(server.URL+"/chunked", http.Header{})
+	require.NoError(t, err)
+	assert.Len(t, mockRespBody(t, response), 100)
+}
+
+func TestHTTPClientMaxResponseHeaderSize(t *testing.T) {
+	dummyHandler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Large", strings.Repeat("a", 4096))
+		w.WriteHeader(http.StatusOK)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(dummyHandler))
+	defer server.Close()
+
+	client := NewClient(WithTimeout(time.Second), WithMaxResponseHeaderSize(1024))
+
+	_, err := client.Get(server.URL, http.Header{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "response headers exceeded")
+}
+
+func TestHTTPClientDrainsDiscardedAttempts(t *testing.T) {
+	count := 0
+	dummyHandler := func(w http.ResponseWriter, r *http.Request) {
+		count++
+		if count == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(strings.Repeat("retry later ", 100)))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(dummyHandler))
+	defer server.Close()
+
+	var reused []bool
+	client := NewClient(
+		WithTimeout(time.Second),
+		WithRetryCount(1),
+		WithHTTPTrace(func(request *http.Request, timings Timings) {
+			reused = append(reused, timings.ConnReused)
+		}),
+	)
+
+	response, err := client.Get(server.URL, http.Header{})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	response.Body.Close()
+
+	assert.Equal(t, []bool{false, true}, reused)
+}
@@ -0,0 +1,91 @@
+package httpclient
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+const acceptEncoding = "gzip, deflate, br"
+
+// compressBody gzips data, it is only used when the compressed body is smaller
+func compressBody(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decodeResponseBody replaces a gzip, deflate or br encoded body with its decoded content
+// and removes the encoding headers, as net/http does for the encodings it handles itself
+func decodeResponseBody(response *http.Response) {
+	encoding := strings.ToLower(strings.TrimSpace(response.Header.Get("Content-Encoding")))
+	switch encoding {
+	case "gzip", "x-gzip", "deflate", "br":
+	default:
+		return
+	}
+
+	response.Body = &decodedBody{body: response.Body, encoding: encoding}
+	response.Header.Del("Content-Encoding")
+	response.Header.Del("Content-Length")
+	response.ContentLength = -1
+	response.Uncompressed = true
+}
+
+// decodedBody creates its decoder on the first Read, so Do does not block on the encoding header
+type decodedBody struct {
+	body     io.ReadCloser
+	encoding string
+	reader   io.Reader
+	err      error
+}
+
+func (b *decodedBody) Read(p []byte) (int, error) {
+	if b.reader == nil && b.err == nil {
+		b.reader, b.err = newDecoder(b.body, b.encoding)
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+
+	return b.reader.Read(p)
+}
+
+func (b *decodedBody) Close() error {
+	if closer, ok := b.reader.(io.Closer); ok {
+		_ = closer.Close()
+	}
+
+	return b.body.Close()
+}
+
+func newDecoder(body io.Reader, encoding string) (io.Reader, error) {
+	switch encoding {
+	case "br":
+		return brotli.NewReader(body), nil
+	case "deflate":
+		// "deflate" should be zlib wrapped, but some servers send raw deflate
+		buffered := bufio.NewReader(body)
+		header, err := buffered.Peek(2)
+		if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+			return zlib.NewReader(buffered)
+		}
+		return flate.NewReader(buffered), nil
+	default:
+		return gzip.NewReader(body)
+	}
+}
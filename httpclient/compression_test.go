@@ -0,0 +1,187 @@
+package httpclient
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeBody(t *testing.T, encoding string, data []byte) []byte {
+	var buf bytes.Buffer
+	var writer io.WriteCloser
+	switch encoding {
+	case "br":
+		writer = brotli.NewWriter(&buf)
+	case "deflate":
+		writer = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		var err error
+		writer, err = flate.NewWriter(&buf, flate.DefaultCompression)
+		require.NoError(t, err)
+	default:
+		writer = gzip.NewWriter(&buf)
+	}
+	_, err := writer.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestCompressionRequestBodyAcrossRetries(t *testing.T) {
+	payload := strings.Repeat(`{"account":"1234567890","amount":10000}`, 50)
+	var calls atomic.Int32
+
+	dummyHandler := func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		assert.Equal(t, acceptEncoding, r.Header.Get("Accept-Encoding"))
+
+		reader, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Equal(t, payload, string(body))
+
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(dummyHandler))
+	defer server.Close()
+
+	client := NewClient(
+		WithTimeout(time.Second),
+		WithRetryCount(3),
+		WithRetrier(NewRetrier(NewConstantBackoff(time.Millisecond, 2*time.Millisecond))),
+		WithCompression(1024),
+	)
+
+	response, err := client.Post(server.URL, strings.NewReader(payload), nil)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestCompressionRequestBodyAcrossRedirect(t *testing.T) {
+	payload := strings.Repeat(`{"account":"1234567890","amount":10000}`, 50)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/transfers", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/v2/transfers", http.StatusTemporaryRedirect)
+	})
+	mux.HandleFunc("/v2/transfers", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+
+		reader, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Equal(t, payload, string(body))
+
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := NewClient(WithTimeout(time.Second), WithCompression(1024))
+
+	response, err := client.Post(server.URL+"/v1/transfers", strings.NewReader(payload), nil)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	assert.Equal(t, http.StatusCreated, response.StatusCode)
+}
+
+func TestCompressionSkipsSmallBody(t *testing.T) {
+	dummyHandler := func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Content-Encoding"))
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "small", string(body))
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(dummyHandler))
+	defer server.Close()
+
+	client := NewClient(WithTimeout(time.Second), WithCompression(1024))
+
+	response, err := client.Post(server.URL, strings.NewReader("small"), nil)
+	require.NoError(t, err)
+	response.Body.Close()
+}
+
+func TestCompressionDecodesResponses(t *testing.T) {
+	expected := strings.Repeat("virtual account paid ", 100)
+
+	for _, encoding := range []string{"gzip", "deflate", "raw-deflate", "br"} {
+		t.Run(encoding, func(t *testing.T) {
+			dummyHandler := func(w http.ResponseWriter, r *http.Request) {
+				header := encoding
+				if encoding == "raw-deflate" {
+					header = "deflate"
+				}
+				w.Header().Set("Content-Encoding", header)
+				w.Write(encodeBody(t, encoding, []byte(expected)))
+			}
+
+			server := httptest.NewServer(http.HandlerFunc(dummyHandler))
+			defer server.Close()
+
+			client := NewClient(WithTimeout(time.Second), WithCompression(1024))
+
+			response, err := client.Get(server.URL, nil)
+			require.NoError(t, err)
+
+			assert.Empty(t, response.Header.Get("Content-Encoding"))
+			assert.Equal(t, expected, mockRespBody(t, response))
+		})
+	}
+}
+
+type rawResponseDoer struct {
+	body []byte
+}
+
+func (d rawResponseDoer) Do(request *http.Request) (*http.Response, error) {
+	header := http.Header{}
+	header.Set("Content-Encoding", "gzip")
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(d.body)),
+		ContentLength: int64(len(d.body)),
+		Request:       request,
+	}, nil
+}
+
+func TestCompressionWithCustomDoer(t *testing.T) {
+	expected := strings.Repeat("settlement report ", 100)
+
+	client := NewClient(
+		WithHTTPClient(rawResponseDoer{body: encodeBody(t, "gzip", []byte(expected))}),
+		WithCompression(1024),
+		WithMaxResponseBodySize(int64(len(expected))),
+	)
+
+	response, err := client.Get("http://partner.local/report", nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(-1), response.ContentLength)
+	assert.Equal(t, expected, mockRespBody(t, response))
+}
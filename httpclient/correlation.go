@@ -0,0 +1,60 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+)
+
+// DefaultCorrelationHeader is the header used by WithCorrelationID when no header is given
+const DefaultCorrelationHeader = "X-Request-ID"
+
+type correlationIDKey struct{}
+
+// ContextWithCorrelationID returns a copy of ctx carrying the correlation ID, e.g. the request
+// ID assigned to the incoming request, so it is forwarded on outgoing calls
+func ContextWithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationIDFromContext returns the correlation ID stored in ctx
+func CorrelationIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(correlationIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// NewCorrelationID returns a random UUID version 4, the default generator of WithCorrelationID
+func NewCorrelationID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		logger.Printf("correlation id generation failed: %v", err)
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// applyCorrelationID sets the correlation header from the request context, or from the
+// generator when the context has none. A header already on the request is kept.
+func (c *CustomHttpClient) applyCorrelationID(request *http.Request) {
+	if c.correlationHeader == "" {
+		return
+	}
+
+	if request.Header == nil {
+		request.Header = http.Header{}
+	}
+
+	if request.Header.Get(c.correlationHeader) != "" {
+		return
+	}
+
+	id, ok := CorrelationIDFromContext(request.Context())
+	if !ok {
+		id = c.correlationIDFunc()
+	}
+
+	request.Header.Set(c.correlationHeader, id)
+}
@@ -0,0 +1,92 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCorrelationIDFromContext(t *testing.T) {
+	var received []string
+	mu := &sync.Mutex{}
+
+	dummyHandler := func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received = append(received, r.Header.Get("X-External-Id"))
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(dummyHandler))
+	defer server.Close()
+
+	client := NewClient(WithTimeout(time.Second), WithCorrelationID("X-EXTERNAL-ID", nil))
+
+	ctx := ContextWithCorrelationID(context.Background(), "req-123")
+	request, err := client.NewRequest(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+
+	response, err := client.Do(request)
+	require.NoError(t, err)
+	response.Body.Close()
+
+	request, err = client.NewRequest(ctx, http.MethodGet, server.URL, nil, WithHeader("X-External-Id", "explicit"))
+	require.NoError(t, err)
+
+	response, err = client.Do(request)
+	require.NoError(t, err)
+	response.Body.Close()
+
+	assert.Equal(t, []string{"req-123", "explicit"}, received)
+
+	id, ok := CorrelationIDFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "req-123", id)
+
+	_, ok = CorrelationIDFromContext(context.Background())
+	assert.False(t, ok)
+}
+
+func TestCorrelationIDGeneratedOncePerCall(t *testing.T) {
+	var calls atomic.Int32
+	var received []string
+	mu := &sync.Mutex{}
+
+	dummyHandler := func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received = append(received, r.Header.Get(DefaultCorrelationHeader))
+		mu.Unlock()
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(dummyHandler))
+	defer server.Close()
+
+	client := NewClient(
+		WithTimeout(time.Second),
+		WithRetryCount(1),
+		WithRetrier(NewRetrier(NewConstantBackoff(time.Millisecond, 2*time.Millisecond))),
+		WithCorrelationID("", nil),
+	)
+
+	response, err := client.Get(server.URL, nil)
+	require.NoError(t, err)
+	response.Body.Close()
+
+	require.Len(t, received, 2)
+	assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), received[0])
+	assert.Equal(t, received[0], received[1])
+	assert.Equal(t, received[0], response.Request.Header.Get(DefaultCorrelationHeader))
+}
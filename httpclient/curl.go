@@ -0,0 +1,208 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const defaultRedactionMask = "***"
+
+// Redaction lists the secrets masked when a request is rendered as a curl command.
+// Names are matched case-insensitively.
+type Redaction struct {
+	Headers     []string
+	QueryParams []string
+	// BodyFields are JSON keys, at any depth, or form fields whose values are masked
+	BodyFields []string
+	// Mask replaces the redacted values, default is "***"
+	Mask string
+}
+
+// DefaultRedaction masks the usual credentials: auth headers, cookies, API keys,
+// signatures, tokens, passwords and PINs
+func DefaultRedaction() Redaction {
+	return Redaction{
+		Headers:     []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Api-Key", "X-Signature", "X-Client-Secret"},
+		QueryParams: []string{"access_token", "api_key", "token", "signature"},
+		BodyFields:  []string{"password", "pin", "secret", "client_secret", "access_token", "refresh_token", "card_number", "cvv"},
+		Mask:        defaultRedactionMask,
+	}
+}
+
+// ToCurl renders request as an equivalent curl command with redaction applied. The request
+// body is read through GetBody when available, otherwise it is read and restored.
+func ToCurl(request *http.Request, redaction Redaction) (string, error) {
+	var body []byte
+	if request.Body != nil && request.Body != http.NoBody {
+		reader := request.Body
+		if request.GetBody != nil {
+			copied, err := request.GetBody()
+			if err != nil {
+				return "", err
+			}
+			reader = copied
+		}
+
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return "", err
+		}
+
+		if request.GetBody == nil {
+			request.Body = io.NopCloser(bytes.NewReader(data))
+		}
+		body = data
+	}
+
+	return renderCurl(request, body, redaction), nil
+}
+
+// renderCurl builds the curl command for request with the given body
+func renderCurl(request *http.Request, body []byte, redaction Redaction) string {
+	mask := redaction.Mask
+	if mask == "" {
+		mask = defaultRedactionMask
+	}
+
+	var cmd strings.Builder
+	cmd.WriteString("curl -X ")
+	cmd.WriteString(request.Method)
+	cmd.WriteString(" ")
+	cmd.WriteString(shellQuote(redactURL(request.URL, redaction.QueryParams, mask)))
+
+	keys := make([]string, 0, len(request.Header))
+	for key := range request.Header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		for _, value := range request.Header[key] {
+			if containsFold(redaction.Headers, key) {
+				value = mask
+			}
+			cmd.WriteString(" \\\n  -H ")
+			cmd.WriteString(shellQuote(key + ": " + value))
+		}
+	}
+
+	if request.Host != "" && request.URL != nil && request.Host != request.URL.Host {
+		cmd.WriteString(" \\\n  -H ")
+		cmd.WriteString(shellQuote("Host: " + request.Host))
+	}
+
+	if len(body) > 0 {
+		cmd.WriteString(" \\\n  --data-raw ")
+		cmd.WriteString(shellQuote(string(redactBody(body, request.Header.Get("Content-Type"), redaction.BodyFields, mask))))
+	}
+
+	return cmd.String()
+}
+
+// shellQuote wraps s in single quotes so it is taken literally by POSIX shells
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactURL(u *url.URL, params []string, mask string) string {
+	if u == nil {
+		return ""
+	}
+
+	redacted := *u
+	redacted.User = nil
+	if len(params) > 0 && redacted.RawQuery != "" {
+		redacted.RawQuery = redactQuery(redacted.RawQuery, params, mask)
+	}
+
+	rendered := redacted.String()
+	// url.UserPassword would escape the mask, so the user info is written by hand
+	if prefix := redacted.Scheme + "://"; u.User != nil && strings.HasPrefix(rendered, prefix) {
+		rendered = prefix + url.User(u.User.Username()).String() + ":" + mask + "@" + rendered[len(prefix):]
+	}
+
+	return rendered
+}
+
+// redactQuery masks the values of params in an encoded query or form. The order of the
+// parameters is kept and the mask is written as is, so it stays readable.
+func redactQuery(rawQuery string, params []string, mask string) string {
+	pairs := strings.Split(rawQuery, "&")
+	for i, pair := range pairs {
+		key, _, _ := strings.Cut(pair, "=")
+		if name, err := url.QueryUnescape(key); err == nil && containsFold(params, name) {
+			pairs[i] = key + "=" + mask
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+// redactBody masks fields of JSON and form encoded bodies, any other body is returned as is
+func redactBody(body []byte, contentType string, fields []string, mask string) []byte {
+	if len(fields) == 0 {
+		return body
+	}
+
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		return []byte(redactQuery(string(body), fields, mask))
+	}
+
+	var doc any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber() // keep large amounts and IDs intact
+	if err := decoder.Decode(&doc); err != nil {
+		return body
+	}
+
+	if !redactJSON(doc, fields, mask) {
+		return body // keep the original formatting when nothing was masked
+	}
+
+	// json.Marshal would escape <, > and & in the payload
+	var redacted bytes.Buffer
+	encoder := json.NewEncoder(&redacted)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(doc); err != nil {
+		return body
+	}
+
+	return bytes.TrimSuffix(redacted.Bytes(), []byte("\n"))
+}
+
+// redactJSON masks matching keys in place and reports whether anything was masked
+func redactJSON(value any, fields []string, mask string) bool {
+	masked := false
+
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			if containsFold(fields, key) {
+				v[key] = mask
+				masked = true
+				continue
+			}
+			masked = redactJSON(item, fields, mask) || masked
+		}
+	case []any:
+		for _, item := range v {
+			masked = redactJSON(item, fields, mask) || masked
+		}
+	}
+
+	return masked
+}
@@ -6,6 +6,10 @@ import (
 	"sync"
 )
 
+// ErrResponseTooLarge is returned when a response body exceeds the limit set with
+// WithMaxResponseBodySize. Check for it with errors.Is.
+var ErrResponseTooLarge = errors.New("response body too large")
+
 // Errors implements error interface. This instance of MultiError has zero or more errors.
 type Errors struct {
 	mutex sync.Mutex // i am using mutex to handle race-cond if anything goes wrong
@@ -0,0 +1,303 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// FaultKind identifies the kind of injected fault
+type FaultKind string
+
+const (
+	FaultConnection FaultKind = "connection"
+	FaultTimeout    FaultKind = "timeout"
+)
+
+// FaultError is returned for injected connection errors and timeouts
+type FaultError struct {
+	Kind FaultKind
+	URL  string
+}
+
+// Error implements error interface.
+func (e *FaultError) Error() string {
+	return fmt.Sprintf("fault injection: %s error for %s", e.Kind, e.URL)
+}
+
+// Timeout reports whether the injected fault is a timeout, like net.Error
+func (e *FaultError) Timeout() bool {
+	return e.Kind == FaultTimeout
+}
+
+// Temporary is part of net.Error, injected faults are always temporary
+func (e *FaultError) Temporary() bool {
+	return true
+}
+
+// FaultRule describes the faults injected for matching requests. Probabilities are
+// between 0 and 1 and are evaluated independently. Durations are in nanoseconds in JSON.
+type FaultRule struct {
+	// Host matches the request host exactly, or with a leading dot the domain and its subdomains. Empty matches any host.
+	Host string `json:"host,omitempty"`
+	// PathPattern is a path.Match pattern, e.g. "/v1/transfers/*". Empty matches any path.
+	PathPattern string `json:"path_pattern,omitempty"`
+
+	Latency            time.Duration `json:"latency,omitempty"`
+	LatencyProbability float64       `json:"latency_probability,omitempty"`
+
+	ConnectionErrorProbability float64 `json:"connection_error_probability,omitempty"`
+
+	// TimeoutAfter is how long a request hangs before the timeout error, bounded by the request context
+	TimeoutAfter       time.Duration `json:"timeout_after,omitempty"`
+	TimeoutProbability float64       `json:"timeout_probability,omitempty"`
+
+	// StatusCode is returned without calling the upstream, it is required with StatusProbability
+	StatusCode        int     `json:"status_code,omitempty"`
+	StatusProbability float64 `json:"status_probability,omitempty"`
+
+	// TruncateAfter is the number of body bytes delivered before the body fails with io.ErrUnexpectedEOF
+	TruncateAfter       int64   `json:"truncate_after,omitempty"`
+	TruncateProbability float64 `json:"truncate_probability,omitempty"`
+}
+
+// validate rejects a rule that would inject an invalid fault
+func (r FaultRule) validate() error {
+	if r.StatusProbability > 0 && (r.StatusCode < 100 || r.StatusCode > 999) {
+		return errors.Errorf("fault rule %s%s: status_probability needs a valid status_code, got %d",
+			r.Host, r.PathPattern, r.StatusCode)
+	}
+
+	return nil
+}
+
+func validateFaultRules(rules []FaultRule) error {
+	for _, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FaultInjector injects faults into requests for chaos testing. It is disabled until
+// Enable is called, and can be switched at runtime, e.g. through its admin http.Handler.
+type FaultInjector struct {
+	mu      sync.RWMutex
+	enabled bool
+	rules   []FaultRule
+	chance  func() float64
+}
+
+// FaultInjectorState is the JSON document served and accepted by the admin handler
+type FaultInjectorState struct {
+	Enabled bool        `json:"enabled"`
+	Rules   []FaultRule `json:"rules"`
+}
+
+// NewFaultInjector returns a disabled fault injector with the given rules, or an error when
+// a rule is invalid
+func NewFaultInjector(rules ...FaultRule) (*FaultInjector, error) {
+	if err := validateFaultRules(rules); err != nil {
+		return nil, err
+	}
+
+	return &FaultInjector{
+		rules:  rules,
+		chance: rand.Float64,
+	}, nil
+}
+
+// Enable starts injecting faults
+func (f *FaultInjector) Enable() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.enabled = true
+}
+
+// Disable stops injecting faults
+func (f *FaultInjector) Disable() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.enabled = false
+}
+
+// Enabled reports whether faults are injected
+func (f *FaultInjector) Enabled() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.enabled
+}
+
+// SetRules replaces the rules. The first rule matching a request is used. The rules are
+// kept unchanged when one of the new rules is invalid.
+func (f *FaultInjector) SetRules(rules ...FaultRule) error {
+	if err := validateFaultRules(rules); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = append([]FaultRule(nil), rules...)
+	return nil
+}
+
+// State returns the current switch and rules
+func (f *FaultInjector) State() FaultInjectorState {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return FaultInjectorState{
+		Enabled: f.enabled,
+		Rules:   append([]FaultRule(nil), f.rules...),
+	}
+}
+
+// ServeHTTP is an admin endpoint. GET returns the FaultInjectorState, PUT or POST replaces it.
+func (f *FaultInjector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		var state FaultInjectorState
+		if err := json.NewDecoder(r.Body).Decode(&state); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := validateFaultRules(state.Rules); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		f.mu.Lock()
+		f.enabled = state.Enabled
+		f.rules = state.Rules
+		f.mu.Unlock()
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentTypeJSON)
+	_ = json.NewEncoder(w).Encode(f.State())
+}
+
+// Wrap returns a DoReq that injects faults before calling next
+func (f *FaultInjector) Wrap(next DoReq) DoReq {
+	return &faultDoer{injector: f, next: next}
+}
+
+// match returns the first rule matching request, if enabled
+func (f *FaultInjector) match(request *http.Request) (FaultRule, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if !f.enabled {
+		return FaultRule{}, false
+	}
+
+	for _, rule := range f.rules {
+		if rule.Host != "" && !matchHost(rule.Host, request.URL.Hostname()) {
+			continue
+		}
+		if rule.PathPattern != "" {
+			if ok, _ := path.Match(rule.PathPattern, request.URL.Path); !ok {
+				continue
+			}
+		}
+		return rule, true
+	}
+
+	return FaultRule{}, false
+}
+
+func (f *FaultInjector) roll(probability float64) bool {
+	return probability > 0 && f.chance() < probability
+}
+
+type faultDoer struct {
+	injector *FaultInjector
+	next     DoReq
+}
+
+// Do implements DoReq
+func (d *faultDoer) Do(request *http.Request) (*http.Response, error) {
+	rule, ok := d.injector.match(request)
+	if !ok {
+		return d.next.Do(request)
+	}
+
+	f := d.injector
+	ctx := request.Context()
+
+	if f.roll(rule.LatencyProbability) {
+		if err := sleepContext(ctx, rule.Latency); err != nil {
+			return nil, err
+		}
+	}
+
+	if f.roll(rule.ConnectionErrorProbability) {
+		return nil, &FaultError{Kind: FaultConnection, URL: request.URL.String()}
+	}
+
+	if f.roll(rule.TimeoutProbability) {
+		_ = sleepContext(ctx, rule.TimeoutAfter)
+		return nil, &FaultError{Kind: FaultTimeout, URL: request.URL.String()}
+	}
+
+	if f.roll(rule.StatusProbability) {
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", rule.StatusCode, http.StatusText(rule.StatusCode)),
+			StatusCode:    rule.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{},
+			Body:          io.NopCloser(bytes.NewReader(nil)),
+			ContentLength: 0,
+			Request:       request,
+		}, nil
+	}
+
+	response, err := d.next.Do(request)
+	if err != nil {
+		return response, err
+	}
+
+	if f.roll(rule.TruncateProbability) {
+		response.Body = &truncatedBody{body: response.Body, remaining: rule.TruncateAfter}
+	}
+
+	return response, nil
+}
+
+// truncatedBody fails with io.ErrUnexpectedEOF once remaining bytes have been read
+type truncatedBody struct {
+	body      io.ReadCloser
+	remaining int64
+}
+
+func (b *truncatedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+
+	n, err := b.body.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+func (b *truncatedBody) Close() error {
+	return b.body.Close()
+}
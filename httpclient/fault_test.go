@@ -0,0 +1,140 @@
+package httpclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func faultServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{ "response": "ok gas" }`))
+	}))
+}
+
+func TestFaultInjectorDisabledByDefault(t *testing.T) {
+	server := faultServer()
+	defer server.Close()
+
+	injector, err := NewFaultInjector(FaultRule{ConnectionErrorProbability: 1})
+	require.NoError(t, err)
+	client := NewClient(WithTimeout(10*time.Millisecond), WithFaultInjector(injector))
+
+	response, err := client.Get(server.URL, http.Header{})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+}
+
+func TestFaultInjectorConnectionErrorAndStatus(t *testing.T) {
+	server := faultServer()
+	defer server.Close()
+
+	injector, err := NewFaultInjector(
+		FaultRule{PathPattern: "/transfers/*", ConnectionErrorProbability: 1},
+		FaultRule{PathPattern: "/inquiry", StatusCode: http.StatusBadGateway, StatusProbability: 1},
+	)
+	require.NoError(t, err)
+	injector.Enable()
+
+	client := NewClient(WithTimeout(10*time.Millisecond), WithFaultInjector(injector))
+
+	_, err = client.Get(server.URL+"/transfers/1", http.Header{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "fault injection: connection error")
+
+	response, err := client.Get(server.URL+"/inquiry", http.Header{})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadGateway, response.StatusCode)
+
+	response, err = client.Get(server.URL+"/other", http.Header{})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+}
+
+func TestFaultInjectorTimeoutAndTruncate(t *testing.T) {
+	server := faultServer()
+	defer server.Close()
+
+	injector, err := NewFaultInjector(FaultRule{Host: "127.0.0.1", TruncateAfter: 5, TruncateProbability: 1})
+	require.NoError(t, err)
+	injector.Enable()
+
+	doer := injector.Wrap(&http.Client{Timeout: time.Second})
+
+	request, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+
+	response, err := doer.Do(request)
+	require.NoError(t, err)
+
+	body, err := io.ReadAll(response.Body)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Equal(t, `{ "re`, string(body))
+
+	require.NoError(t, injector.SetRules(FaultRule{TimeoutProbability: 1, TimeoutAfter: time.Millisecond}))
+	_, err = doer.Do(request)
+	require.Error(t, err)
+
+	faultErr, ok := err.(*FaultError)
+	require.True(t, ok)
+	assert.True(t, faultErr.Timeout())
+}
+
+func TestFaultInjectorAdminHandler(t *testing.T) {
+	injector, err := NewFaultInjector()
+	require.NoError(t, err)
+
+	request := httptest.NewRequest(http.MethodPut, "/admin/faults",
+		strings.NewReader(`{ "enabled": true, "rules": [{ "host": ".partner.com", "status_code": 503, "status_probability": 0.5 }] }`))
+	recorder := httptest.NewRecorder()
+	injector.ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.True(t, injector.Enabled())
+
+	state := injector.State()
+	require.Len(t, state.Rules, 1)
+	assert.Equal(t, 503, state.Rules[0].StatusCode)
+
+	recorder = httptest.NewRecorder()
+	injector.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "/admin/faults", strings.NewReader("{")))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	// a status fault without a status code is rejected and the rules are kept
+	recorder = httptest.NewRecorder()
+	injector.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "/admin/faults",
+		strings.NewReader(`{ "enabled": true, "rules": [{ "status_probability": 1 }] }`)))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, 503, injector.State().Rules[0].StatusCode)
+}
+
+func TestFaultInjectorRejectsStatusWithoutCode(t *testing.T) {
+	_, err := NewFaultInjector(FaultRule{StatusProbability: 0.5})
+	require.Error(t, err)
+
+	injector, err := NewFaultInjector(FaultRule{StatusCode: http.StatusBadGateway, StatusProbability: 1})
+	require.NoError(t, err)
+	require.Error(t, injector.SetRules(FaultRule{StatusCode: 42, StatusProbability: 1}))
+	assert.Equal(t, http.StatusBadGateway, injector.State().Rules[0].StatusCode)
+}
+
+func TestFaultInjectorHostCaseInsensitive(t *testing.T) {
+	injector, err := NewFaultInjector(FaultRule{Host: ".Partner.COM", ConnectionErrorProbability: 1})
+	require.NoError(t, err)
+	injector.Enable()
+
+	request, err := http.NewRequest(http.MethodGet, "https://API.partner.com/v1/transfers", nil)
+	require.NoError(t, err)
+
+	_, err = injector.Wrap(&http.Client{Timeout: time.Second}).Do(request)
+	var faultErr *FaultError
+	require.ErrorAs(t, err, &faultErr)
+	assert.Equal(t, FaultConnection, faultErr.Kind)
+}
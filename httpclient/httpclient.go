@@ -2,11 +2,13 @@ package httpclient
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"os"
 	"time"
-
-	"github.com/pkg/errors"
 )
 
 type CustomHttpClient struct {
@@ -15,6 +17,32 @@ type CustomHttpClient struct {
 	// for retry mechanism
 	retrier    Retriable
 	retryCount int
+	// for building the default transport
+	transportConfig *TransportConfig
+	// applied to every request
+	baseURL        string
+	defaultHeaders http.Header
+	// for spreading attempts across multiple endpoints
+	balancer *Balancer
+	// for chaos testing
+	faultInjector *FaultInjector
+	// for connection level timings
+	traceEnabled  bool
+	traceCallback TraceCallback
+	// for logging failed attempts as curl commands
+	curlDebug     bool
+	curlRedaction Redaction
+	curlLogFunc   func(message string)
+	// 0 means no limit
+	maxResponseBodySize int64
+	// for gzip request bodies and decoding compressed responses
+	compression          bool
+	compressionThreshold int
+	// for forwarding the correlation ID
+	correlationHeader string
+	correlationIDFunc func() string
+	// for cookie sessions with automatic login
+	session *session
 }
 
 const (
@@ -22,6 +50,8 @@ const (
 	defaultTimeout    = 30 * time.Second
 )
 
+var logger = log.New(os.Stdout, "httpclient:", log.LstdFlags)
+
 var _ HttpMethods = (*CustomHttpClient)(nil)
 
 // NewClient returns a new instance of http Client
@@ -37,23 +67,53 @@ func NewClient(opts ...Option) *CustomHttpClient {
 	}
 
 	if client.client == nil {
-		client.client = &http.Client{
+		httpClient := &http.Client{
 			Timeout: client.timeout,
 		}
+		if client.transportConfig != nil {
+			httpClient.Transport = NewTransport(*client.transportConfig)
+		}
+		client.client = httpClient
+	}
+
+	if client.session != nil {
+		// a custom *http.Client without a jar gets a copy with the session jar, any other
+		// client gets the session cookies added and stored by do
+		if httpClient, ok := client.client.(*http.Client); ok && httpClient.Jar == nil {
+			withJar := *httpClient
+			withJar.Jar = client.session.jar
+			client.client = &withJar
+		} else {
+			client.session.attachCookies = true
+		}
+	}
+
+	if client.faultInjector != nil {
+		client.client = client.faultInjector.Wrap(client.client)
 	}
 
 	return &client
 }
 
+// ensureTransportConfig returns the transport config, creating a default one if needed
+func (c *CustomHttpClient) ensureTransportConfig() *TransportConfig {
+	if c.transportConfig == nil {
+		cfg := DefaultTransportConfig()
+		c.transportConfig = &cfg
+	}
+
+	return c.transportConfig
+}
+
 // Get makes a HTTP GET request to provided URL
 func (c *CustomHttpClient) Get(url string, headers http.Header) (*http.Response, error) {
 	var response *http.Response
-	request, err := http.NewRequest(http.MethodGet, url, nil)
+	request, err := c.NewRequest(context.Background(), http.MethodGet, url, nil)
 	if err != nil {
-		return response, errors.Wrap(err, "GET - request process failed")
+		return response, err
 	}
 
-	request.Header = headers
+	request.Header = headers.Clone()
 
 	return c.Do(request)
 }
@@ -61,12 +121,12 @@ func (c *CustomHttpClient) Get(url string, headers http.Header) (*http.Response,
 // Post makes a HTTP POST request to provided URL and requestBody
 func (c *CustomHttpClient) Post(url string, body io.Reader, headers http.Header) (*http.Response, error) {
 	var response *http.Response
-	request, err := http.NewRequest(http.MethodPost, url, body)
+	request, err := c.NewRequest(context.Background(), http.MethodPost, url, body)
 	if err != nil {
-		return response, errors.Wrap(err, "POST - request process failed")
+		return response, err
 	}
 
-	request.Header = headers
+	request.Header = headers.Clone()
 
 	return c.Do(request)
 }
@@ -74,12 +134,12 @@ func (c *CustomHttpClient) Post(url string, body io.Reader, headers http.Header)
 // Put makes a HTTP PUT request to provided URL and requestBody
 func (c *CustomHttpClient) Put(url string, body io.Reader, headers http.Header) (*http.Response, error) {
 	var response *http.Response
-	request, err := http.NewRequest(http.MethodPut, url, body)
+	request, err := c.NewRequest(context.Background(), http.MethodPut, url, body)
 	if err != nil {
-		return response, errors.Wrap(err, "PUT - request process failed")
+		return response, err
 	}
 
-	request.Header = headers
+	request.Header = headers.Clone()
 
 	return c.Do(request)
 }
@@ -87,12 +147,12 @@ func (c *CustomHttpClient) Put(url string, body io.Reader, headers http.Header)
 // Patch makes a HTTP PATCH request to provided URL and requestBody
 func (c *CustomHttpClient) Patch(url string, body io.Reader, headers http.Header) (*http.Response, error) {
 	var response *http.Response
-	request, err := http.NewRequest(http.MethodPatch, url, body)
+	request, err := c.NewRequest(context.Background(), http.MethodPatch, url, body)
 	if err != nil {
-		return response, errors.Wrap(err, "PATCH - request process failed")
+		return response, err
 	}
 
-	request.Header = headers
+	request.Header = headers.Clone()
 
 	return c.Do(request)
 }
@@ -100,64 +160,206 @@ func (c *CustomHttpClient) Patch(url string, body io.Reader, headers http.Header
 // Delete makes a HTTP DELETE request with provided URL
 func (c *CustomHttpClient) Delete(url string, headers http.Header) (*http.Response, error) {
 	var response *http.Response
-	request, err := http.NewRequest(http.MethodDelete, url, nil)
+	request, err := c.NewRequest(context.Background(), http.MethodDelete, url, nil)
 	if err != nil {
-		return response, errors.Wrap(err, "DELETE - request process failed")
+		return response, err
 	}
 
-	request.Header = headers
+	request.Header = headers.Clone()
 
 	return c.Do(request)
 }
 
 // Do makes an HTTP request with `http.Do`
 func (c *CustomHttpClient) Do(request *http.Request) (*http.Response, error) {
+	if c.session != nil {
+		return c.session.do(c, request)
+	}
+
+	return c.do(request)
+}
+
+func (c *CustomHttpClient) do(request *http.Request) (*http.Response, error) {
+	c.applyDefaultHeaders(request)
+	c.applyCorrelationID(request)
+
 	var bodyReader *bytes.Reader
+	var reqData []byte
+	compressed := false
+
+	if c.compression && request.Header == nil {
+		request.Header = http.Header{}
+	}
 
 	if request.Body != nil {
-		reqData, err := io.ReadAll(request.Body)
+		var err error
+		reqData, err = io.ReadAll(request.Body)
 		if err != nil {
 			return nil, err
 		}
 
-		bodyReader = bytes.NewReader(reqData)
+		sendData := reqData
+		// compress once so every retry sends the same bytes
+		if c.compression && len(reqData) >= c.compressionThreshold && request.Header.Get("Content-Encoding") == "" {
+			if gzipped, err := compressBody(reqData); err == nil && len(gzipped) < len(reqData) {
+				sendData = gzipped
+				compressed = true
+				request.Header.Set("Content-Encoding", "gzip")
+				request.ContentLength = int64(len(gzipped))
+			}
+		}
+
+		bodyReader = bytes.NewReader(sendData)
 		request.Body = io.NopCloser(bodyReader) // prevents closing the body between retries
+		// a 307 or 308 redirect sends the body again, it must be the same bytes as ContentLength
+		request.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(sendData)), nil
+		}
+	}
+
+	if c.compression && request.Header.Get("Accept-Encoding") == "" {
+		request.Header.Set("Accept-Encoding", acceptEncoding)
 	}
 
 	multiErr := &Errors{}
 	var response *http.Response
+	originalURL := request.URL
+	tried := map[*endpointState]bool{}
 
 	for i := 0; i <= c.retryCount; i++ {
 		if response != nil {
-			response.Body.Close()
+			drainAndClose(response.Body) // lets the connection be reused by the next attempt
+		}
+
+		// pick an endpoint for this attempt, a retry fails over to an endpoint not tried yet
+		var endpoint *endpointState
+		if c.balancer != nil {
+			endpoint = c.balancer.pick(tried)
+			tried[endpoint] = true
+			request.URL = endpoint.resolve(originalURL)
+			request.Host = ""
+		}
+
+		// a cookie jar adds its cookies to the request being sent, so every attempt sends
+		// a copy to keep them from piling up in the header across retries
+		attemptRequest := request.Clone(request.Context())
+		var tracer *attemptTracer
+		if c.traceEnabled {
+			attemptRequest, tracer = traceAttempt(attemptRequest, i, c.traceCallback)
+		}
+
+		if c.session != nil && c.session.attachCookies {
+			c.session.addCookies(attemptRequest)
 		}
 
 		var err error
-		response, err = c.client.Do(request)
+		response, err = c.client.Do(attemptRequest)
+		if tracer != nil {
+			tracer.done(response, err)
+		}
+		if err == nil && c.session != nil && c.session.attachCookies {
+			c.session.storeCookies(attemptRequest, response)
+		}
+		if err == nil && c.compression {
+			decodeResponseBody(response)
+		}
+		if err == nil && c.maxResponseBodySize > 0 {
+			if limitErr := limitResponseBody(response, c.maxResponseBodySize); limitErr != nil {
+				return nil, limitErr // not retried, the next attempt would be as large
+			}
+		}
 		if bodyReader != nil {
 			// Reset the body reader after the request since at this point it's already read
 			// Note that it's safe to ignore the error here since the 0,0 position is always valid
 			_, _ = bodyReader.Seek(0, 0)
 		}
 
+		failure := attemptFailure(response, err)
+		if endpoint != nil {
+			c.balancer.report(endpoint, failure)
+		}
+		if c.curlDebug && failure != nil {
+			c.logCurl(attemptRequest, reqData, compressed, i, failure)
+		}
+
 		if err != nil {
 			multiErr.Push(err.Error())
+		} else if response.StatusCode < http.StatusInternalServerError {
+			multiErr = &Errors{} // Clear ALL errors if any iteration process succeeds
+			break
+		}
 
-			backoffTime := c.retrier.NextInterval(i)
-			time.Sleep(backoffTime)
+		if i == c.retryCount {
+			break // no backoff after the last attempt
+		}
 
-			continue
+		backoffTime := c.retrier.NextInterval(i)
+		if err := sleepContext(request.Context(), backoffTime); err != nil {
+			if response != nil {
+				drainAndClose(response.Body)
+			}
+			return nil, err
 		}
+	}
+
+	return response, multiErr.HasError()
+}
+
+// EndpointHealth returns the health of every balanced endpoint, or nil without WithBalancer
+func (c *CustomHttpClient) EndpointHealth() []EndpointHealth {
+	if c.balancer == nil {
+		return nil
+	}
+
+	return c.balancer.Health()
+}
+
+// attemptFailure returns the error that counts against an endpoint for a single attempt
+func attemptFailure(response *http.Response, err error) error {
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("server error status code %d", response.StatusCode)
+	}
+
+	return nil
+}
 
-		if response.StatusCode >= http.StatusInternalServerError {
-			backoffTime := c.retrier.NextInterval(i)
-			time.Sleep(backoffTime)
-			continue
+// logCurl logs a failed attempt as a curl command that reproduces it
+func (c *CustomHttpClient) logCurl(request *http.Request, body []byte, compressed bool, attempt int, failure error) {
+	// the session cookies are only on the request when an *http.Client added them while sending it
+	var cookies []*http.Cookie
+	if jar := c.CookieJar(); jar != nil {
+		sent := map[string]bool{}
+		for _, cookie := range request.Cookies() {
+			sent[cookie.Name] = true
 		}
+		for _, cookie := range jar.Cookies(request.URL) {
+			if !sent[cookie.Name] {
+				cookies = append(cookies, cookie)
+			}
+		}
+	}
+
+	if compressed || len(cookies) > 0 {
+		request = request.Clone(request.Context())
+	}
+	if compressed {
+		// body is the uncompressed payload, so the command must not claim it is gzipped
+		request.Header.Del("Content-Encoding")
+	}
+	for _, cookie := range cookies {
+		request.AddCookie(cookie)
+	}
 
-		multiErr = &Errors{} // Clear ALL errors if any iteration process succeeds
-		break
+	message := fmt.Sprintf("attempt %d failed: %s\n%s", attempt, failure.Error(), renderCurl(request, body, c.curlRedaction))
+
+	if c.curlLogFunc != nil {
+		c.curlLogFunc(message)
+		return
 	}
 
-	return response, multiErr.HasError()
+	logger.Println(message)
 }
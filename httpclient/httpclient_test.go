@@ -2,6 +2,7 @@ package httpclient
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -246,6 +247,36 @@ func TestHTTPClientGetRetriesOnFailure(t *testing.T) {
 	assert.Equal(t, numOfCalls, count)
 }
 
+func TestHTTPClientRetryBackoffHonorsContext(t *testing.T) {
+	count := 0
+	dummyHandler := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		count++
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(dummyHandler))
+	defer server.Close()
+
+	client := NewClient(
+		WithTimeout(time.Second),
+		WithRetryCount(3),
+		WithRetrier(NewRetrierFunc(func(int) time.Duration { return time.Hour })),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+
+	start := time.Now()
+	response, err := client.Do(request)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Nil(t, response)
+	assert.Less(t, time.Since(start), time.Second, "the backoff must stop when the context is done")
+	assert.Equal(t, 1, count)
+}
+
 func TestHTTPClientPostRetriesOnFailure(t *testing.T) {
 	count := 0
 	numOfRetries := 3
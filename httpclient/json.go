@@ -0,0 +1,130 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+const contentTypeJSON = "application/json"
+
+// StatusError is returned by the JSON helpers when the response status is not 2xx.
+// The raw body is kept so it can be decoded with ErrorBody.
+type StatusError struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Error implements error interface.
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code %d: %s", e.StatusCode, string(e.Body))
+}
+
+// ErrorBody decodes the body of a *StatusError into E. It returns false when err is not
+// a *StatusError or the body cannot be decoded.
+func ErrorBody[E any](err error) (E, bool) {
+	var body E
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return body, false
+	}
+
+	if err := json.Unmarshal(statusErr.Body, &body); err != nil {
+		return body, false
+	}
+
+	return body, true
+}
+
+// DoJSON encodes body as JSON, sends it through c.Do and decodes a 2xx response into Resp.
+// Any other status returns a *StatusError. A nil body, including a nil pointer, sends no
+// request body. The Accept header is only set when the options did not set it.
+func DoJSON[Req, Resp any](ctx context.Context, c *CustomHttpClient, method, path string, body Req, opts ...RequestOption) (Resp, error) {
+	var result Resp
+
+	var reader io.Reader
+	if !isNilBody(body) {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return result, errors.Wrap(err, "failed to encode request body")
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	request, err := c.NewRequest(ctx, method, path, reader, opts...)
+	if err != nil {
+		return result, err
+	}
+
+	if request.Header.Get("Accept") == "" {
+		request.Header.Set("Accept", contentTypeJSON)
+	}
+	if reader != nil && request.Header.Get("Content-Type") == "" {
+		request.Header.Set("Content-Type", contentTypeJSON)
+	}
+
+	response, err := c.Do(request)
+	if err != nil {
+		if response != nil {
+			drainAndClose(response.Body)
+		}
+		return result, err
+	}
+	defer response.Body.Close()
+
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return result, errors.Wrap(err, "failed to read response body")
+	}
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return result, &StatusError{
+			StatusCode: response.StatusCode,
+			Header:     response.Header,
+			Body:       respBody,
+		}
+	}
+
+	if len(bytes.TrimSpace(respBody)) == 0 {
+		return result, nil
+	}
+
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return result, errors.Wrap(err, "failed to decode response body")
+	}
+
+	return result, nil
+}
+
+// isNilBody reports whether body is nil or a nil pointer, map, slice or interface
+func isNilBody(body any) bool {
+	if body == nil {
+		return true
+	}
+
+	v := reflect.ValueOf(body)
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+		return v.IsNil()
+	}
+
+	return false
+}
+
+// GetJSON makes a GET request and decodes the JSON response into Resp
+func GetJSON[Resp any](ctx context.Context, c *CustomHttpClient, path string, opts ...RequestOption) (Resp, error) {
+	return DoJSON[any, Resp](ctx, c, http.MethodGet, path, nil, opts...)
+}
+
+// PostJSON makes a POST request with a JSON body and decodes the JSON response into Resp
+func PostJSON[Req, Resp any](ctx context.Context, c *CustomHttpClient, path string, body Req, opts ...RequestOption) (Resp, error) {
+	return DoJSON[Req, Resp](ctx, c, http.MethodPost, path, body, opts...)
+}
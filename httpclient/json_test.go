@@ -0,0 +1,177 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type transferRequest struct {
+	Amount int `json:"amount"`
+}
+
+type transferResponse struct {
+	ID     string `json:"id"`
+	Amount int    `json:"amount"`
+}
+
+type partnerError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func TestResolveURL(t *testing.T) {
+	client := NewClient(WithBaseURL("https://api.partner.com/v1/?key=abc"))
+
+	resolved, err := client.ResolveURL("/accounts/1?page=2")
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.partner.com/v1/accounts/1?key=abc&page=2", resolved)
+
+	resolved, err = client.ResolveURL("https://other.com/x")
+	require.NoError(t, err)
+	assert.Equal(t, "https://other.com/x", resolved)
+
+	// escaped path segments, the query order and the fragment are kept
+	resolved, err = client.ResolveURL("/files/a%2Fb?z=1&a=2#top")
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.partner.com/v1/files/a%2Fb?key=abc&z=1&a=2#top", resolved)
+
+	// WithQuery appends to the query of the path without reordering it
+	request, err := client.NewRequest(context.Background(), http.MethodGet, "/files?z=1&a=%2F", nil,
+		WithQuery(url.Values{"page": {"2"}, "limit": {"10"}}))
+	require.NoError(t, err)
+	assert.Equal(t, "key=abc&z=1&a=%2F&limit=10&page=2", request.URL.RawQuery)
+}
+
+func TestHTTPClientDefaultHeaders(t *testing.T) {
+	dummyHandler := func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/ping", r.URL.Path)
+		assert.Equal(t, "secret", r.Header.Get("X-Api-Key"))
+		assert.Equal(t, "id", r.Header.Get("Accept-Language"))
+		w.WriteHeader(http.StatusOK)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(dummyHandler))
+	defer server.Close()
+
+	client := NewClient(
+		WithTimeout(10*time.Millisecond),
+		WithBaseURL(server.URL+"/v1"),
+		WithDefaultHeaders(http.Header{"X-Api-Key": {"secret"}, "Accept-Language": {"en"}}),
+	)
+
+	headers := http.Header{}
+	headers.Set("Accept-Language", "id")
+
+	response, err := client.Get("ping", headers)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "", headers.Get("X-Api-Key"), "caller headers must not be modified")
+}
+
+func TestDoJSONSuccess(t *testing.T) {
+	dummyHandler := func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/transfers", r.URL.Path)
+		assert.Equal(t, "true", r.URL.Query().Get("dry_run"))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		var body transferRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, 1000, body.Amount)
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{ "id": "trx-1", "amount": 1000 }`))
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(dummyHandler))
+	defer server.Close()
+
+	client := NewClient(WithTimeout(10*time.Millisecond), WithBaseURL(server.URL))
+
+	resp, err := PostJSON[transferRequest, transferResponse](context.Background(), client, "/transfers",
+		transferRequest{Amount: 1000}, WithQuery(url.Values{"dry_run": {"true"}}))
+	require.NoError(t, err)
+	assert.Equal(t, transferResponse{ID: "trx-1", Amount: 1000}, resp)
+}
+
+func TestDoJSONStatusError(t *testing.T) {
+	dummyHandler := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{ "code": "INVALID_AMOUNT", "message": "amount must be positive" }`))
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(dummyHandler))
+	defer server.Close()
+
+	client := NewClient(WithTimeout(10*time.Millisecond), WithBaseURL(server.URL))
+
+	_, err := GetJSON[transferResponse](context.Background(), client, "/transfers/1")
+	require.Error(t, err)
+
+	statusErr, ok := err.(*StatusError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusBadRequest, statusErr.StatusCode)
+
+	body, ok := ErrorBody[partnerError](err)
+	require.True(t, ok)
+	assert.Equal(t, "INVALID_AMOUNT", body.Code)
+}
+
+func TestDoJSONHeadersAndNilBody(t *testing.T) {
+	dummyHandler := func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/vnd.partner+json", r.Header.Get("Accept"))
+		assert.Equal(t, "", r.Header.Get("Content-Type"))
+		assert.Equal(t, int64(0), r.ContentLength, "a nil pointer must not be sent as null")
+		w.Write([]byte(`{ "id": "trx-1" }`))
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(dummyHandler))
+	defer server.Close()
+
+	client := NewClient(WithTimeout(time.Second), WithBaseURL(server.URL))
+
+	var body *transferRequest
+	resp, err := DoJSON[*transferRequest, transferResponse](context.Background(), client, http.MethodDelete,
+		"/transfers/1", body, WithHeader("Accept", "application/vnd.partner+json"))
+	require.NoError(t, err)
+	assert.Equal(t, "trx-1", resp.ID)
+}
+
+// failingDoer returns a response together with an error, like a client giving up on retries
+type failingDoer struct {
+	body *closeRecorder
+}
+
+func (d failingDoer) Do(*http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusBadGateway, Body: d.body}, errors.New("partner unavailable")
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (r *closeRecorder) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestDoJSONClosesResponseOnError(t *testing.T) {
+	body := &closeRecorder{Reader: strings.NewReader("bad gateway")}
+	client := NewClient(WithHTTPClient(failingDoer{body: body}), WithBaseURL("http://partner.local"))
+
+	_, err := GetJSON[transferResponse](context.Background(), client, "/transfers/1")
+	require.Error(t, err)
+	assert.True(t, body.closed)
+}
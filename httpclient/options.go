@@ -1,6 +1,10 @@
 package httpclient
 
-import "time"
+import (
+	"net/http"
+	"net/url"
+	"time"
+)
 
 type Option func(*CustomHttpClient)
 
@@ -29,3 +33,139 @@ func WithHTTPClient(client DoReq) Option {
 		c.client = client
 	}
 }
+
+// WithTransportConfig builds the underlying transport from cfg. Non-zero fields are merged
+// over DefaultTransportConfig and the other transport options, whatever their order. It is
+// ignored when WithHTTPClient is used since the custom client brings its own transport.
+func WithTransportConfig(cfg TransportConfig) Option {
+	return func(c *CustomHttpClient) {
+		c.ensureTransportConfig().merge(cfg)
+	}
+}
+
+// WithProxy routes every request through the given HTTP(S) or SOCKS5 proxy
+func WithProxy(proxy *url.URL) Option {
+	return func(c *CustomHttpClient) {
+		c.ensureTransportConfig().Proxy = proxy
+	}
+}
+
+// WithHostProxy routes requests for host through the given proxy. A host with a leading
+// dot, e.g. ".bank.co.id", also matches its subdomains.
+func WithHostProxy(host string, proxy *url.URL) Option {
+	return func(c *CustomHttpClient) {
+		cfg := c.ensureTransportConfig()
+		if cfg.HostProxies == nil {
+			cfg.HostProxies = map[string]*url.URL{}
+		}
+		cfg.HostProxies[host] = proxy
+	}
+}
+
+// WithBaseURL sets the base URL that relative request paths are resolved against
+func WithBaseURL(baseURL string) Option {
+	return func(c *CustomHttpClient) {
+		c.baseURL = baseURL
+	}
+}
+
+// WithDefaultHeaders sets headers sent with every request unless the request already has them
+func WithDefaultHeaders(headers http.Header) Option {
+	return func(c *CustomHttpClient) {
+		if c.defaultHeaders == nil {
+			c.defaultHeaders = http.Header{}
+		}
+		for key, values := range headers {
+			c.defaultHeaders[http.CanonicalHeaderKey(key)] = append([]string(nil), values...)
+		}
+	}
+}
+
+// WithBalancer spreads attempts across the balancer endpoints. The request path and query
+// are appended to the selected endpoint URL, so requests can be built with a relative path.
+func WithBalancer(balancer *Balancer) Option {
+	return func(c *CustomHttpClient) {
+		c.balancer = balancer
+	}
+}
+
+// WithFaultInjector wraps the underlying DoReq, including one set with WithHTTPClient,
+// with the fault injector. Faults are only injected after the injector is enabled.
+func WithFaultInjector(injector *FaultInjector) Option {
+	return func(c *CustomHttpClient) {
+		c.faultInjector = injector
+	}
+}
+
+// WithHTTPTrace attaches net/http/httptrace hooks to every attempt. The timings are available
+// through TimingsFromResponse, and are passed to callback, which may be nil, when the attempt completes.
+func WithHTTPTrace(callback TraceCallback) Option {
+	return func(c *CustomHttpClient) {
+		c.traceEnabled = true
+		c.traceCallback = callback
+	}
+}
+
+// WithCurlDebug logs every failed attempt (transport error or 5xx) as a curl command with
+// redaction applied. logFunc receives the message, when nil it is logged to stdout.
+func WithCurlDebug(redaction Redaction, logFunc func(message string)) Option {
+	return func(c *CustomHttpClient) {
+		c.curlDebug = true
+		c.curlRedaction = redaction
+		c.curlLogFunc = logFunc
+	}
+}
+
+// WithMaxResponseBodySize limits response bodies to maxBytes. A larger Content-Length fails
+// Do right away, otherwise reading past the limit fails. Both errors wrap ErrResponseTooLarge.
+func WithMaxResponseBodySize(maxBytes int64) Option {
+	return func(c *CustomHttpClient) {
+		c.maxResponseBodySize = maxBytes
+	}
+}
+
+// WithMaxResponseHeaderSize limits the size of response headers. Like WithTransportConfig,
+// it is ignored when WithHTTPClient is used.
+func WithMaxResponseHeaderSize(maxBytes int64) Option {
+	return func(c *CustomHttpClient) {
+		c.ensureTransportConfig().MaxResponseHeaderBytes = maxBytes
+	}
+}
+
+// WithCompression gzips request bodies of at least minSize bytes and sets Content-Encoding.
+// It also asks for and decodes gzip, deflate and br responses, also when WithHTTPClient is used.
+// Body size limits apply to the decoded body.
+func WithCompression(minSize int) Option {
+	return func(c *CustomHttpClient) {
+		c.compression = true
+		c.compressionThreshold = minSize
+	}
+}
+
+// WithCorrelationID forwards the correlation ID stored with ContextWithCorrelationID in the
+// given header, DefaultCorrelationHeader when header is empty. When the request context has
+// no ID, generate creates one, NewCorrelationID when generate is nil. The ID is set once per
+// Do call, so every retry carries the same value.
+func WithCorrelationID(header string, generate func() string) Option {
+	return func(c *CustomHttpClient) {
+		if header == "" {
+			header = DefaultCorrelationHeader
+		}
+		if generate == nil {
+			generate = NewCorrelationID
+		}
+		c.correlationHeader = header
+		c.correlationIDFunc = generate
+	}
+}
+
+// WithSession enables the cookie session mode. Cookies are kept in a jar per client,
+// persisted to config.Store when set, and config.Login is called when the session has
+// expired. A client in session mode is safe for concurrent use, concurrent requests that
+// find the session expired share a single login. With WithHTTPClient, an *http.Client
+// without a jar gets the session jar; any other client gets the cookies added and stored per attempt.
+func WithSession(config SessionConfig) Option {
+	return func(c *CustomHttpClient) {
+		c.session = &session{config: config, jar: newSessionJar(config.Store)}
+	}
+}
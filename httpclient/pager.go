@@ -0,0 +1,413 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// PageStrategy decides how pages are requested
+type PageStrategy interface {
+	// First sets the parameters of the first page on u
+	First(u *url.URL)
+	// Next returns the URL of the page after current, or nil when there are no more pages.
+	// body is the raw response body and itemCount the number of items decoded from it.
+	Next(current *url.URL, response *http.Response, body []byte, itemCount int) (*url.URL, error)
+}
+
+// PagerConfig configures NewPager
+type PagerConfig[T any] struct {
+	Strategy PageStrategy
+	// Items extracts the items of a page, default decodes the body as a JSON array. See ItemsField.
+	Items func(body []byte) ([]T, error)
+	// MaxPages stops the pager after this many pages, 0 means unlimited. A strategy returning
+	// a page URL that was already fetched, e.g. a repeated cursor, stops the pager with an error.
+	MaxPages int
+	// Options are applied to every page request, e.g. headers or the query of the first page
+	Options []RequestOption
+}
+
+// Pager iterates over the items of a paginated list endpoint. Every page goes through Do, so
+// it is retried with the client retrier. Use it as a loop:
+//
+//	for pager.Next(ctx) {
+//		item := pager.Item()
+//	}
+//	if err := pager.Err(); err != nil {
+//		...
+//	}
+type Pager[T any] struct {
+	client  *CustomHttpClient
+	path    string
+	config  PagerConfig[T]
+	nextURL *url.URL
+	fetched map[string]bool // URLs of the pages fetched so far
+	started bool
+	done    bool
+	pages   int
+	items   []T
+	index   int
+	err     error
+}
+
+// NewPager returns a pager starting at path, relative to the client base URL
+func NewPager[T any](c *CustomHttpClient, path string, config PagerConfig[T]) *Pager[T] {
+	if config.Items == nil {
+		config.Items = func(body []byte) ([]T, error) {
+			var items []T
+			err := json.Unmarshal(body, &items)
+			return items, err
+		}
+	}
+
+	return &Pager[T]{
+		client:  c,
+		path:    path,
+		config:  config,
+		fetched: make(map[string]bool),
+		index:   -1,
+	}
+}
+
+// Next advances to the next item, fetching the next page when needed. It returns false when
+// there are no more items, MaxPages is reached, ctx is done or an error occurred.
+func (p *Pager[T]) Next(ctx context.Context) bool {
+	for {
+		if p.err != nil {
+			return false
+		}
+
+		if p.index+1 < len(p.items) {
+			p.index++
+			return true
+		}
+
+		items, ok := p.NextPage(ctx)
+		if !ok {
+			return false
+		}
+
+		p.items = items
+		p.index = -1
+	}
+}
+
+// Item returns the current item, valid after Next returned true
+func (p *Pager[T]) Item() T {
+	return p.items[p.index]
+}
+
+// Err returns the error that stopped the iteration, if any
+func (p *Pager[T]) Err() error {
+	return p.err
+}
+
+// Pages returns the number of pages fetched so far
+func (p *Pager[T]) Pages() int {
+	return p.pages
+}
+
+// NextPage fetches and returns the items of the next page. It returns false when there are no
+// more pages, MaxPages is reached, ctx is done or an error occurred.
+func (p *Pager[T]) NextPage(ctx context.Context) ([]T, bool) {
+	if p.err != nil || p.done {
+		return nil, false
+	}
+
+	if p.config.MaxPages > 0 && p.pages >= p.config.MaxPages {
+		p.done = true
+		return nil, false
+	}
+
+	if err := ctx.Err(); err != nil {
+		p.err = err
+		return nil, false
+	}
+
+	items, err := p.fetch(ctx)
+	if err != nil {
+		p.err = err
+		return nil, false
+	}
+
+	return items, true
+}
+
+// Seq returns an iterator over the items with the signature of iter.Seq2, so it can be used
+// with range over func in Go 1.23+, or called with a yield function in Go 1.22. Iteration stops
+// after the first error is yielded.
+func (p *Pager[T]) Seq(ctx context.Context) func(yield func(T, error) bool) {
+	return func(yield func(T, error) bool) {
+		for p.Next(ctx) {
+			if !yield(p.Item(), nil) {
+				return
+			}
+		}
+
+		if err := p.Err(); err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
+
+// All collects the items of every page
+func (p *Pager[T]) All(ctx context.Context) ([]T, error) {
+	var all []T
+	for {
+		items, ok := p.NextPage(ctx)
+		if !ok {
+			return all, p.err
+		}
+		all = append(all, items...)
+	}
+}
+
+func (p *Pager[T]) fetch(ctx context.Context) ([]T, error) {
+	request, err := p.client.NewRequest(ctx, http.MethodGet, p.path, nil, p.config.Options...)
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.started {
+		p.started = true
+		if p.config.Strategy != nil {
+			p.config.Strategy.First(request.URL)
+		}
+	} else {
+		request.URL = p.nextURL
+		request.Host = ""
+	}
+	current := request.URL
+	p.fetched[current.String()] = true
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		if response != nil {
+			drainAndClose(response.Body)
+		}
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read page body")
+	}
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return nil, &StatusError{StatusCode: response.StatusCode, Header: response.Header, Body: body}
+	}
+
+	items, err := p.config.Items(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode page items")
+	}
+	p.pages++
+
+	var next *url.URL
+	if p.config.Strategy != nil {
+		next, err = p.config.Strategy.Next(current, response, body, len(items))
+		if err != nil {
+			return nil, err
+		}
+		if next != nil && p.fetched[next.String()] {
+			return nil, errors.Errorf("pager: next page %s was already fetched, the cursor does not advance", next.Redacted())
+		}
+	}
+
+	p.nextURL = next
+	p.done = next == nil
+
+	return items, nil
+}
+
+// ItemsField decodes the items of a page from a JSON field, using dots for nested
+// fields, e.g. "data.items"
+func ItemsField[T any](field string) func(body []byte) ([]T, error) {
+	return func(body []byte) ([]T, error) {
+		var items []T
+
+		raw, err := lookupJSON(body, field)
+		if err != nil || raw == nil {
+			return items, err
+		}
+
+		err = json.Unmarshal(raw, &items)
+		return items, err
+	}
+}
+
+// lookupJSON returns the raw value at a dotted path, or nil when it does not exist
+func lookupJSON(body []byte, path string) (json.RawMessage, error) {
+	raw := json.RawMessage(body)
+	for _, key := range strings.Split(path, ".") {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &object); err != nil {
+			return nil, err
+		}
+
+		value, ok := object[key]
+		if !ok || string(value) == "null" {
+			return nil, nil
+		}
+		raw = value
+	}
+
+	return raw, nil
+}
+
+// LinkHeaderStrategy follows the rel="next" URL of the RFC 5988 Link header
+type LinkHeaderStrategy struct{}
+
+// First implements PageStrategy
+func (LinkHeaderStrategy) First(*url.URL) {}
+
+// Next implements PageStrategy
+func (LinkHeaderStrategy) Next(current *url.URL, response *http.Response, _ []byte, _ int) (*url.URL, error) {
+	for _, header := range response.Header.Values("Link") {
+		for _, link := range strings.Split(header, ",") {
+			target, params, ok := strings.Cut(strings.TrimSpace(link), ";")
+			if !ok || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+
+			for _, param := range strings.Split(params, ";") {
+				name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if name != "rel" {
+					continue
+				}
+
+				for _, rel := range strings.Fields(strings.Trim(value, `"`)) {
+					if rel == "next" {
+						ref, err := url.Parse(strings.Trim(target, "<>"))
+						if err != nil {
+							return nil, errors.Wrap(err, "invalid next link")
+						}
+						return current.ResolveReference(ref), nil
+					}
+				}
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// CursorStrategy reads the next cursor from the JSON body and sends it as a query parameter.
+// The pager stops when the cursor is missing or empty.
+type CursorStrategy struct {
+	CursorField string // dotted path of the next cursor in the body, e.g. "meta.next_cursor"
+	Param       string // query parameter carrying the cursor, e.g. "cursor"
+}
+
+// First implements PageStrategy
+func (CursorStrategy) First(*url.URL) {}
+
+// Next implements PageStrategy
+func (s CursorStrategy) Next(current *url.URL, _ *http.Response, body []byte, _ int) (*url.URL, error) {
+	raw, err := lookupJSON(body, s.CursorField)
+	if err != nil || raw == nil {
+		return nil, err
+	}
+
+	// a number is sent as written, a float64 would round IDs above 2^53
+	var cursor any
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&cursor); err != nil {
+		return nil, err
+	}
+
+	var value string
+	switch v := cursor.(type) {
+	case string:
+		value = v
+	case json.Number:
+		value = v.String()
+	}
+
+	if value == "" {
+		return nil, nil
+	}
+
+	return withQueryParam(current, s.Param, value), nil
+}
+
+// PageNumberStrategy increments a page number parameter. The pager stops at an empty page,
+// or at a page with fewer than Size items when Size is set.
+type PageNumberStrategy struct {
+	Param     string // e.g. "page"
+	Start     int    // first page number, usually 0 or 1
+	SizeParam string // optional, e.g. "per_page"
+	Size      int
+}
+
+// First implements PageStrategy
+func (s PageNumberStrategy) First(u *url.URL) {
+	*u = *withQueryParam(u, s.Param, strconv.Itoa(s.Start))
+	if s.SizeParam != "" && s.Size > 0 {
+		*u = *withQueryParam(u, s.SizeParam, strconv.Itoa(s.Size))
+	}
+}
+
+// Next implements PageStrategy
+func (s PageNumberStrategy) Next(current *url.URL, _ *http.Response, _ []byte, itemCount int) (*url.URL, error) {
+	if itemCount == 0 || (s.Size > 0 && itemCount < s.Size) {
+		return nil, nil
+	}
+
+	page, err := strconv.Atoi(current.Query().Get(s.Param))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid page number")
+	}
+
+	return withQueryParam(current, s.Param, strconv.Itoa(page+1)), nil
+}
+
+// OffsetStrategy moves an offset parameter forward by the number of items received.
+// The pager stops at a page with fewer than Limit items.
+type OffsetStrategy struct {
+	Param      string // e.g. "offset"
+	LimitParam string // e.g. "limit"
+	Limit      int
+}
+
+// First implements PageStrategy
+func (s OffsetStrategy) First(u *url.URL) {
+	*u = *withQueryParam(u, s.Param, "0")
+	if s.LimitParam != "" && s.Limit > 0 {
+		*u = *withQueryParam(u, s.LimitParam, strconv.Itoa(s.Limit))
+	}
+}
+
+// Next implements PageStrategy
+func (s OffsetStrategy) Next(current *url.URL, _ *http.Response, _ []byte, itemCount int) (*url.URL, error) {
+	if itemCount == 0 || (s.Limit > 0 && itemCount < s.Limit) {
+		return nil, nil
+	}
+
+	offset, err := strconv.Atoi(current.Query().Get(s.Param))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid offset")
+	}
+
+	return withQueryParam(current, s.Param, strconv.Itoa(offset+itemCount)), nil
+}
+
+// withQueryParam returns a copy of u with key set to value
+func withQueryParam(u *url.URL, key, value string) *url.URL {
+	updated := *u
+	query := updated.Query()
+	query.Set(key, value)
+	updated.RawQuery = query.Encode()
+
+	return &updated
+}
@@ -0,0 +1,196 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type account struct {
+	ID int `json:"id"`
+}
+
+// pagedHandler serves 5 accounts, 2 per page, with page numbers starting at 1
+func pagedHandler(t *testing.T, write func(w http.ResponseWriter, r *http.Request, page int, items string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, err := strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil {
+			page = 1
+		}
+		assert.Equal(t, "secret", r.Header.Get("X-Api-Key"))
+
+		items := map[int]string{1: `[{"id":1},{"id":2}]`, 2: `[{"id":3},{"id":4}]`, 3: `[{"id":5}]`}[page]
+		if items == "" {
+			items = "[]"
+		}
+		write(w, r, page, items)
+	}
+}
+
+func collectIDs(t *testing.T, pager *Pager[account]) []int {
+	var ids []int
+	for pager.Next(context.Background()) {
+		ids = append(ids, pager.Item().ID)
+	}
+	require.NoError(t, pager.Err())
+	return ids
+}
+
+func TestPagerLinkHeader(t *testing.T) {
+	server := httptest.NewServer(pagedHandler(t, func(w http.ResponseWriter, r *http.Request, page int, items string) {
+		if page < 3 {
+			w.Header().Set("Link", fmt.Sprintf(`</accounts?page=%d>; rel="next", </accounts?page=1>; rel="first"`, page+1))
+		}
+		w.Write([]byte(items))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithTimeout(time.Second), WithBaseURL(server.URL))
+	pager := NewPager(client, "/accounts", PagerConfig[account]{
+		Strategy: LinkHeaderStrategy{},
+		Options:  []RequestOption{WithHeader("X-Api-Key", "secret")},
+	})
+
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, collectIDs(t, pager))
+	assert.Equal(t, 3, pager.Pages())
+}
+
+func TestPagerCursor(t *testing.T) {
+	server := httptest.NewServer(pagedHandler(t, func(w http.ResponseWriter, r *http.Request, _ int, _ string) {
+		cursor := r.URL.Query().Get("cursor")
+		id := map[string]int{"": 1, "c2": 2, "c3": 3}[cursor]
+		next := map[string]string{"": "c2", "c2": "c3", "c3": ""}[cursor]
+		fmt.Fprintf(w, `{"data":{"items":[{"id":%d}]},"meta":{"next_cursor":%q}}`, id, next)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithTimeout(time.Second), WithBaseURL(server.URL), WithDefaultHeaders(http.Header{"X-Api-Key": {"secret"}}))
+	pager := NewPager(client, "/accounts", PagerConfig[account]{
+		Strategy: CursorStrategy{CursorField: "meta.next_cursor", Param: "cursor"},
+		Items:    ItemsField[account]("data.items"),
+	})
+
+	items, err := pager.All(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []account{{ID: 1}, {ID: 2}, {ID: 3}}, items)
+}
+
+func TestPagerLargeNumericCursor(t *testing.T) {
+	var cursors []string
+	server := httptest.NewServer(pagedHandler(t, func(w http.ResponseWriter, r *http.Request, _ int, _ string) {
+		cursor := r.URL.Query().Get("after_id")
+		cursors = append(cursors, cursor)
+		if cursor == "" {
+			// above 2^53, a float64 would turn it into 9007199254740992
+			fmt.Fprint(w, `{"data":{"items":[{"id":1}]},"meta":{"next_cursor":9007199254740993}}`)
+			return
+		}
+		fmt.Fprint(w, `{"data":{"items":[{"id":2}]},"meta":{"next_cursor":null}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithTimeout(time.Second), WithBaseURL(server.URL), WithDefaultHeaders(http.Header{"X-Api-Key": {"secret"}}))
+	pager := NewPager(client, "/accounts", PagerConfig[account]{
+		Strategy: CursorStrategy{CursorField: "meta.next_cursor", Param: "after_id"},
+		Items:    ItemsField[account]("data.items"),
+	})
+
+	items, err := pager.All(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []account{{ID: 1}, {ID: 2}}, items)
+	assert.Equal(t, []string{"", "9007199254740993"}, cursors)
+}
+
+func TestPagerStopsOnRepeatedCursor(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(pagedHandler(t, func(w http.ResponseWriter, r *http.Request, _ int, _ string) {
+		count++
+		// the partner keeps returning the same cursor
+		fmt.Fprint(w, `{"data":{"items":[{"id":1}]},"meta":{"next_cursor":"c2"}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithTimeout(time.Second), WithBaseURL(server.URL), WithDefaultHeaders(http.Header{"X-Api-Key": {"secret"}}))
+	pager := NewPager(client, "/accounts", PagerConfig[account]{
+		Strategy: CursorStrategy{CursorField: "meta.next_cursor", Param: "cursor"},
+		Items:    ItemsField[account]("data.items"),
+	})
+
+	items, err := pager.All(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already fetched")
+	assert.Equal(t, []account{{ID: 1}}, items)
+	assert.Equal(t, 2, count)
+}
+
+func TestPagerPageNumberWithMaxPages(t *testing.T) {
+	server := httptest.NewServer(pagedHandler(t, func(w http.ResponseWriter, r *http.Request, _ int, items string) {
+		assert.Equal(t, "2", r.URL.Query().Get("per_page"))
+		w.Write([]byte(items))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithTimeout(time.Second), WithBaseURL(server.URL), WithDefaultHeaders(http.Header{"X-Api-Key": {"secret"}}))
+
+	pager := NewPager(client, "/accounts", PagerConfig[account]{
+		Strategy: PageNumberStrategy{Param: "page", Start: 1, SizeParam: "per_page", Size: 2},
+	})
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, collectIDs(t, pager))
+
+	pager = NewPager(client, "/accounts", PagerConfig[account]{
+		Strategy: PageNumberStrategy{Param: "page", Start: 1, SizeParam: "per_page", Size: 2},
+		MaxPages: 2,
+	})
+	assert.Equal(t, []int{1, 2, 3, 4}, collectIDs(t, pager))
+}
+
+func TestPagerOffset(t *testing.T) {
+	server := httptest.NewServer(pagedHandler(t, func(w http.ResponseWriter, r *http.Request, _ int, _ string) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		items := map[int]string{0: `[{"id":1},{"id":2}]`, 2: `[{"id":3}]`}[offset]
+		w.Write([]byte(items))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithTimeout(time.Second), WithBaseURL(server.URL), WithDefaultHeaders(http.Header{"X-Api-Key": {"secret"}}))
+	pager := NewPager(client, "/accounts", PagerConfig[account]{
+		Strategy: OffsetStrategy{Param: "offset", LimitParam: "limit", Limit: 2},
+	})
+
+	var ids []int
+	pager.Seq(context.Background())(func(item account, err error) bool {
+		require.NoError(t, err)
+		ids = append(ids, item.ID)
+		return true
+	})
+	assert.Equal(t, []int{1, 2, 3}, ids)
+}
+
+func TestPagerStopsOnErrorAndCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithTimeout(time.Second), WithBaseURL(server.URL))
+
+	pager := NewPager(client, "/accounts", PagerConfig[account]{Strategy: LinkHeaderStrategy{}})
+	assert.False(t, pager.Next(context.Background()))
+	statusErr, ok := pager.Err().(*StatusError)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusUnauthorized, statusErr.StatusCode)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	pager = NewPager(client, "/accounts", PagerConfig[account]{Strategy: LinkHeaderStrategy{}})
+	assert.False(t, pager.Next(ctx))
+	assert.ErrorIs(t, pager.Err(), context.Canceled)
+}
@@ -0,0 +1,84 @@
+package httpclient
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket allowing rate events per second with bursts of up to burst
+// events. A token is checked for and taken under a lock, so concurrent callers never share one.
+type RateLimiter struct {
+	mu       sync.Mutex
+	rate     float64
+	burst    float64
+	tokens   float64
+	lastFill time.Time
+}
+
+// NewRateLimiter returns a RateLimiter with a full bucket, a burst below 1 is 1
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		rate:     rate,
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		lastFill: time.Now(),
+	}
+}
+
+// Wait blocks until it took a token or ctx is done
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		wait := l.Reserve()
+		if wait == 0 {
+			return nil
+		}
+
+		if err := sleepContext(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// Reserve takes a token if one is available, otherwise it returns how long to wait for one
+// and takes nothing
+func (l *RateLimiter) Reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.fill()
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+
+	wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+	if wait <= 0 {
+		wait = time.Microsecond // 0 means a token was taken
+	}
+
+	return wait
+}
+
+// Refund gives back a token taken for an event that did not happen
+func (l *RateLimiter) Refund() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.fill()
+	l.tokens = min(l.tokens+1, l.burst)
+}
+
+// fill adds the tokens earned since the last fill, mu must be held
+func (l *RateLimiter) fill() {
+	now := time.Now()
+	l.tokens += now.Sub(l.lastFill).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.lastFill = now
+}
@@ -0,0 +1,33 @@
+package httpclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	limiter := NewRateLimiter(20, 2)
+
+	// the burst is available right away, the next token comes after 50ms
+	assert.Zero(t, limiter.Reserve())
+	assert.Zero(t, limiter.Reserve())
+	wait := limiter.Reserve()
+	assert.Greater(t, wait, 40*time.Millisecond)
+	assert.LessOrEqual(t, wait, 50*time.Millisecond)
+
+	// a refunded token can be taken again
+	limiter.Refund()
+	assert.Zero(t, limiter.Reserve())
+
+	start := time.Now()
+	require.NoError(t, limiter.Wait(context.Background()))
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, limiter.Wait(ctx), context.Canceled)
+}
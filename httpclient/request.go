@@ -0,0 +1,120 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// RequestOption customizes a single request built by NewRequest or the JSON helpers
+type RequestOption func(*http.Request)
+
+// WithQuery adds the given query parameters to the request URL. The parameters already in
+// the URL are kept as they are, the new ones are appended sorted by key.
+func WithQuery(query url.Values) RequestOption {
+	return func(r *http.Request) {
+		encoded := query.Encode()
+		switch {
+		case encoded == "":
+		case r.URL.RawQuery == "":
+			r.URL.RawQuery = encoded
+		default:
+			r.URL.RawQuery += "&" + encoded
+		}
+	}
+}
+
+// WithHeader sets a header on the request, overriding the client default headers
+func WithHeader(key, value string) RequestOption {
+	return func(r *http.Request) {
+		r.Header.Set(key, value)
+	}
+}
+
+// NewRequest builds a request for path relative to the client base URL. An absolute
+// URL in path is used as is. Default headers are applied when the request is sent.
+func (c *CustomHttpClient) NewRequest(ctx context.Context, method, path string, body io.Reader, opts ...RequestOption) (*http.Request, error) {
+	fullURL, err := c.ResolveURL(path)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, fullURL, body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s - request process failed", method)
+	}
+
+	for _, opt := range opts {
+		opt(request)
+	}
+
+	return request, nil
+}
+
+// ResolveURL joins path to the client base URL. An absolute URL is returned unchanged.
+func (c *CustomHttpClient) ResolveURL(path string) (string, error) {
+	if c.baseURL == "" {
+		return path, nil
+	}
+
+	base, err := url.Parse(c.baseURL)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid base URL")
+	}
+
+	ref, err := url.Parse(path)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid request path")
+	}
+
+	if ref.IsAbs() {
+		return path, nil
+	}
+
+	return joinURL(base, ref).String(), nil
+}
+
+// joinURL appends the path of ref to the path of base and the query of ref to the query
+// of base. The escaping of the path and the order of the query are kept, the fragment is
+// the one of ref.
+func joinURL(base, ref *url.URL) *url.URL {
+	resolved := *base
+	resolved.Path = strings.TrimRight(base.Path, "/") + "/" + strings.TrimLeft(ref.Path, "/")
+	// EscapedPath falls back to escaping Path when RawPath is not a valid encoding of it
+	resolved.RawPath = strings.TrimRight(base.EscapedPath(), "/") + "/" + strings.TrimLeft(ref.EscapedPath(), "/")
+
+	switch {
+	case resolved.RawQuery == "":
+		resolved.RawQuery = ref.RawQuery
+	case ref.RawQuery != "":
+		resolved.RawQuery += "&" + ref.RawQuery
+	}
+	resolved.ForceQuery = false
+	resolved.Fragment, resolved.RawFragment = ref.Fragment, ref.RawFragment
+
+	return &resolved
+}
+
+// applyDefaultHeaders sets the client default headers that are not already on the request
+func (c *CustomHttpClient) applyDefaultHeaders(request *http.Request) {
+	if len(c.defaultHeaders) == 0 {
+		return
+	}
+
+	if request.Header == nil {
+		request.Header = http.Header{}
+	}
+
+	for key, values := range c.defaultHeaders {
+		if request.Header.Get(key) != "" {
+			continue
+		}
+		for _, value := range values {
+			request.Header.Add(key, value)
+		}
+	}
+}
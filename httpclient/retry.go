@@ -1,6 +1,9 @@
 package httpclient
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // Retriable defines contract for retriers to implement
 type Retriable interface {
@@ -47,3 +50,20 @@ func NewNoRetrier() Retriable {
 func (r *noRetrier) NextInterval(retry int) time.Duration {
 	return 0 * time.Second
 }
+
+// sleepContext waits for d, returning early with the context error when ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,351 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// LoginFunc logs the session in, e.g. by posting the portal login form with the client.
+// Requests sent by LoginFunc must use ctx, so they are not checked for an expired session.
+type LoginFunc func(ctx context.Context, client *CustomHttpClient) error
+
+// SessionConfig configures the cookie session mode enabled with WithSession
+type SessionConfig struct {
+	// Login is called when the session has expired, the request is then sent once more
+	Login LoginFunc
+	// LoginPath is the path of the login page. A response redirecting to it, or that was
+	// redirected to it, means the session has expired. A 401 status always does.
+	LoginPath string
+	// IsExpired replaces the 401 and LoginPath detection
+	IsExpired func(response *http.Response) bool
+	// Store persists the cookies so the session survives a restart, nil keeps them in memory
+	Store CookieStore
+}
+
+// StoredCookie is a cookie kept by a CookieStore with the URL that set it
+type StoredCookie struct {
+	URL    string      `json:"url"`
+	Cookie http.Cookie `json:"cookie"`
+}
+
+// CookieStore persists the session cookies
+type CookieStore interface {
+	Load() ([]StoredCookie, error)
+	Save(cookies []StoredCookie) error
+}
+
+// FileCookieStore keeps the session cookies in a JSON file readable only by its owner
+type FileCookieStore struct {
+	path string
+}
+
+// NewFileCookieStore returns a CookieStore writing to path
+func NewFileCookieStore(path string) *FileCookieStore {
+	return &FileCookieStore{path: path}
+}
+
+// Load implements CookieStore, a missing file is an empty session
+func (s *FileCookieStore) Load() ([]StoredCookie, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "read cookie store")
+	}
+
+	var cookies []StoredCookie
+	if err := json.Unmarshal(data, &cookies); err != nil {
+		return nil, errors.Wrap(err, "decode cookie store")
+	}
+
+	return cookies, nil
+}
+
+// Save implements CookieStore, the file is replaced atomically
+func (s *FileCookieStore) Save(cookies []StoredCookie) error {
+	data, err := json.Marshal(cookies)
+	if err != nil {
+		return errors.Wrap(err, "encode cookie store")
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "save cookie store")
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "save cookie store")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "save cookie store")
+	}
+
+	return errors.Wrap(os.Rename(tmp.Name(), s.path), "save cookie store")
+}
+
+// sessionJar is a cookie jar that also records the cookies it is given, because
+// cookiejar.Jar cannot list them, so they can be saved to the CookieStore
+type sessionJar struct {
+	jar   *cookiejar.Jar
+	store CookieStore
+
+	mu      sync.Mutex
+	cookies map[string]StoredCookie
+}
+
+func newSessionJar(store CookieStore) *sessionJar {
+	jar, _ := cookiejar.New(nil) // never fails without options
+	s := &sessionJar{jar: jar, store: store, cookies: make(map[string]StoredCookie)}
+
+	if store == nil {
+		return s
+	}
+
+	stored, err := store.Load()
+	if err != nil {
+		logger.Printf("session cookies not restored: %v", err)
+		return s
+	}
+
+	for _, cookie := range stored {
+		u, err := url.Parse(cookie.URL)
+		if err != nil || isExpired(&cookie.Cookie) {
+			continue
+		}
+		cookie := cookie
+		s.jar.SetCookies(u, []*http.Cookie{&cookie.Cookie})
+		s.cookies[cookieKey(u, &cookie.Cookie)] = cookie
+	}
+
+	return s
+}
+
+// Cookies implements http.CookieJar
+func (s *sessionJar) Cookies(u *url.URL) []*http.Cookie {
+	return s.jar.Cookies(u)
+}
+
+// SetCookies implements http.CookieJar
+func (s *sessionJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	s.jar.SetCookies(u, cookies)
+
+	if s.store == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, cookie := range cookies {
+		key := cookieKey(u, cookie)
+		if cookie.MaxAge < 0 || isExpired(cookie) {
+			delete(s.cookies, key)
+			continue
+		}
+		s.cookies[key] = StoredCookie{URL: u.String(), Cookie: *cookie}
+	}
+
+	stored := make([]StoredCookie, 0, len(s.cookies))
+	for _, cookie := range s.cookies {
+		stored = append(stored, cookie)
+	}
+
+	if err := s.store.Save(stored); err != nil {
+		logger.Printf("session cookies not saved: %v", err)
+	}
+}
+
+func cookieKey(u *url.URL, cookie *http.Cookie) string {
+	return u.Hostname() + "|" + cookie.Domain + "|" + cookie.Path + "|" + cookie.Name
+}
+
+func isExpired(cookie *http.Cookie) bool {
+	return !cookie.Expires.IsZero() && cookie.Expires.Before(time.Now())
+}
+
+type sessionLoginKey struct{}
+
+// session detects expired sessions and logs in again, once for all the requests
+// that noticed it at the same time
+type session struct {
+	config SessionConfig
+	jar    *sessionJar
+	// attachCookies is set when the client does not send through the session jar,
+	// e.g. a custom DoReq or an *http.Client with its own jar
+	attachCookies bool
+
+	mu         sync.Mutex
+	generation int           // incremented after every successful login
+	loggingIn  chan struct{} // closed when the running login is done
+	loginErr   error
+}
+
+func (s *session) do(c *CustomHttpClient, request *http.Request) (*http.Response, error) {
+	if request.Header == nil {
+		request.Header = http.Header{} // the jar adds the cookies to it
+	}
+
+	ctx := request.Context()
+	if ctx.Value(sessionLoginKey{}) != nil || s.config.Login == nil {
+		return c.do(request)
+	}
+
+	// keep the body so the request can be sent again after the login
+	var body []byte
+	if request.Body != nil {
+		var err error
+		if body, err = io.ReadAll(request.Body); err != nil {
+			return nil, err
+		}
+		request.Body.Close()
+		request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	replay := request.Clone(ctx)
+
+	s.mu.Lock()
+	generation := s.generation
+	s.mu.Unlock()
+
+	response, err := c.do(request)
+	if err != nil || !s.expired(request, response) {
+		return response, err
+	}
+
+	drainAndClose(response.Body)
+	if err := s.login(ctx, c, generation); err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		replay.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	return c.do(replay)
+}
+
+// addCookies adds the session cookies the request does not carry yet
+func (s *session) addCookies(request *http.Request) {
+	sent := map[string]bool{}
+	for _, cookie := range request.Cookies() {
+		sent[cookie.Name] = true
+	}
+
+	for _, cookie := range s.jar.Cookies(request.URL) {
+		if !sent[cookie.Name] {
+			request.AddCookie(cookie)
+		}
+	}
+}
+
+// storeCookies keeps the cookies set by the response in the session jar
+func (s *session) storeCookies(request *http.Request, response *http.Response) {
+	cookies := response.Cookies()
+	if len(cookies) == 0 {
+		return
+	}
+
+	u := request.URL
+	if response.Request != nil && response.Request.URL != nil {
+		u = response.Request.URL // the last request of a followed redirect
+	}
+	s.jar.SetCookies(u, cookies)
+}
+
+// login runs the LoginFunc unless a login already succeeded after generation
+func (s *session) login(ctx context.Context, c *CustomHttpClient, generation int) error {
+	s.mu.Lock()
+	if s.generation != generation {
+		s.mu.Unlock()
+		return nil
+	}
+
+	if wait := s.loggingIn; wait != nil {
+		s.mu.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.loginErr
+	}
+
+	done := make(chan struct{})
+	s.loggingIn = done
+	s.mu.Unlock()
+
+	err := s.config.Login(context.WithValue(ctx, sessionLoginKey{}, true), c)
+	if err != nil {
+		err = errors.Wrap(err, "session login failed")
+	}
+
+	s.mu.Lock()
+	if err == nil {
+		s.generation++
+	}
+	s.loginErr = err
+	s.loggingIn = nil
+	close(done)
+	s.mu.Unlock()
+
+	return err
+}
+
+func (s *session) expired(request *http.Request, response *http.Response) bool {
+	if s.config.IsExpired != nil {
+		return s.config.IsExpired(response)
+	}
+
+	if response.StatusCode == http.StatusUnauthorized {
+		return true
+	}
+
+	if s.config.LoginPath == "" || request.URL.Path == s.config.LoginPath {
+		return false
+	}
+
+	if location, err := response.Location(); err == nil && location.Path == s.config.LoginPath {
+		return true
+	}
+
+	return response.Request != nil && response.Request.URL.Path == s.config.LoginPath
+}
+
+// Login logs the session in with the SessionConfig Login function, e.g. before the first
+// request. It returns an error when WithSession is not used.
+func (c *CustomHttpClient) Login(ctx context.Context) error {
+	if c.session == nil || c.session.config.Login == nil {
+		return errors.New("session login is not configured")
+	}
+
+	c.session.mu.Lock()
+	generation := c.session.generation
+	c.session.mu.Unlock()
+
+	return c.session.login(ctx, c, generation)
+}
+
+// CookieJar returns the session cookie jar, nil when WithSession is not used
+func (c *CustomHttpClient) CookieJar() http.CookieJar {
+	if c.session == nil {
+		return nil
+	}
+
+	return c.session.jar
+}
@@ -0,0 +1,240 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// portalServer is a legacy portal that keeps one valid session at a time
+type portalServer struct {
+	session  atomic.Int32
+	logins   atomic.Int32
+	redirect bool // redirect to the login page instead of answering 401
+}
+
+func (p *portalServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/login" {
+		if r.Method == http.MethodPost {
+			id := p.logins.Add(1)
+			p.session.Store(id)
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: strconv.Itoa(int(id)), Path: "/"})
+		}
+		w.Write([]byte("login page"))
+		return
+	}
+
+	cookie, err := r.Cookie("sid")
+	if err != nil || cookie.Value != strconv.Itoa(int(p.session.Load())) {
+		if p.redirect {
+			http.Redirect(w, r, "/login", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	w.Write([]byte("mutations " + string(body)))
+}
+
+func portalLogin(ctx context.Context, client *CustomHttpClient) error {
+	request, err := client.NewRequest(ctx, http.MethodPost, "/login", strings.NewReader("user=ops&password=secret"))
+	if err != nil {
+		return err
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	_, err = io.Copy(io.Discard, response.Body)
+	return err
+}
+
+func TestSessionReloginOnUnauthorized(t *testing.T) {
+	portal := &portalServer{}
+	server := httptest.NewServer(portal)
+	defer server.Close()
+
+	client := NewClient(
+		WithTimeout(time.Second),
+		WithBaseURL(server.URL),
+		WithSession(SessionConfig{Login: portalLogin, LoginPath: "/login"}),
+	)
+
+	request, err := client.NewRequest(context.Background(), http.MethodPost, "/mutations", strings.NewReader("2024-08"))
+	require.NoError(t, err)
+
+	response, err := client.Do(request)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "mutations 2024-08", mockRespBody(t, response))
+	assert.Equal(t, int32(1), portal.logins.Load())
+
+	// the portal dropped the session, concurrent requests share one login
+	portal.session.Store(0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response, err := client.Get("/mutations", nil)
+			if assert.NoError(t, err) {
+				assert.Equal(t, http.StatusOK, response.StatusCode)
+				response.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(2), portal.logins.Load())
+}
+
+func TestSessionReloginOnLoginRedirect(t *testing.T) {
+	portal := &portalServer{redirect: true}
+	server := httptest.NewServer(portal)
+	defer server.Close()
+
+	client := NewClient(
+		WithTimeout(time.Second),
+		WithBaseURL(server.URL),
+		WithSession(SessionConfig{Login: portalLogin, LoginPath: "/login"}),
+	)
+
+	response, err := client.Get("/mutations", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "mutations ", mockRespBody(t, response))
+	assert.Equal(t, int32(1), portal.logins.Load())
+}
+
+func TestSessionLoginFailure(t *testing.T) {
+	portal := &portalServer{}
+	server := httptest.NewServer(portal)
+	defer server.Close()
+
+	client := NewClient(
+		WithBaseURL(server.URL),
+		WithSession(SessionConfig{Login: func(ctx context.Context, client *CustomHttpClient) error {
+			return assert.AnError
+		}}),
+	)
+
+	_, err := client.Get("/mutations", nil)
+	assert.ErrorIs(t, err, assert.AnError)
+
+	assert.Error(t, NewClient().Login(context.Background()))
+}
+
+func TestSessionCookiePersistence(t *testing.T) {
+	portal := &portalServer{}
+	server := httptest.NewServer(portal)
+	defer server.Close()
+
+	store := NewFileCookieStore(filepath.Join(t.TempDir(), "cookies.json"))
+	config := SessionConfig{Login: portalLogin, Store: store}
+
+	first := NewClient(WithBaseURL(server.URL), WithSession(config))
+	require.NoError(t, first.Login(context.Background()))
+
+	stored, err := store.Load()
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	assert.Equal(t, "sid", stored[0].Cookie.Name)
+
+	// a restarted process reuses the saved session without logging in
+	second := NewClient(WithBaseURL(server.URL), WithSession(config))
+	response, err := second.Get("/mutations", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	response.Body.Close()
+
+	assert.Equal(t, int32(1), portal.logins.Load())
+	assert.NotNil(t, second.CookieJar())
+	assert.Nil(t, NewClient().CookieJar())
+}
+
+func TestSessionCookieNotRepeatedOnRetry(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		cookies []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "abc", Path: "/"})
+			return
+		}
+		mu.Lock()
+		cookies = append(cookies, r.Header.Get("Cookie"))
+		mu.Unlock()
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := NewClient(
+		WithBaseURL(server.URL),
+		WithRetryCount(2),
+		WithSession(SessionConfig{Login: portalLogin}),
+	)
+	require.NoError(t, client.Login(context.Background()))
+
+	response, err := client.Get("/mutations", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, response.StatusCode)
+	response.Body.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"sid=abc", "sid=abc", "sid=abc"}, cookies)
+}
+
+func TestSessionWithCustomClient(t *testing.T) {
+	ownJar, _ := cookiejar.New(nil)
+	clients := map[string]DoReq{
+		"custom DoReq":              &myCustomHTTPClient{client: http.Client{Timeout: time.Second}},
+		"*http.Client with own jar": &http.Client{Timeout: time.Second, Jar: ownJar},
+	}
+
+	for name, doer := range clients {
+		t.Run(name, func(t *testing.T) {
+			portal := &portalServer{}
+			server := httptest.NewServer(portal)
+			defer server.Close()
+
+			client := NewClient(
+				WithBaseURL(server.URL),
+				WithHTTPClient(doer),
+				WithSession(SessionConfig{Login: portalLogin, LoginPath: "/login"}),
+			)
+
+			for i := 0; i < 2; i++ {
+				response, err := client.Get("/mutations", nil)
+				require.NoError(t, err)
+				assert.Equal(t, http.StatusOK, response.StatusCode)
+				response.Body.Close()
+			}
+
+			// logged in once, the second request reused the session cookie
+			assert.Equal(t, int32(1), portal.logins.Load())
+			serverURL, err := url.Parse(server.URL)
+			require.NoError(t, err)
+			assert.Len(t, client.CookieJar().Cookies(serverURL), 1)
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package httpclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const defaultSSERetry = 3 * time.Second
+
+// Stream delivers items decoded from a streaming response. Items are read from C until
+// it is closed, then Err reports why the stream ended.
+type Stream[T any] struct {
+	items chan T
+	err   error
+}
+
+// C returns the channel of decoded items, closed when the stream ends
+func (s *Stream[T]) C() <-chan T {
+	return s.items
+}
+
+// Err returns the error that ended the stream, or nil when it ended normally or the
+// context was cancelled. It must only be called after C is closed.
+func (s *Stream[T]) Err() error {
+	return s.err
+}
+
+// Event is a single Server-Sent Event
+type Event struct {
+	ID    string
+	Event string // event type, "message" when the server does not send one
+	Data  string
+}
+
+// SSEConfig configures StreamSSE
+type SSEConfig struct {
+	// Backoff is used between reconnects. A retry field sent by the server takes precedence.
+	// Default is a constant 3 seconds as recommended by the SSE specification.
+	Backoff Backoff
+	// LastEventID resumes a previous subscription
+	LastEventID string
+	// MaxReconnects stops the stream after this many consecutive failed reconnects, 0 means unlimited
+	MaxReconnects int
+	// BufferSize of the events channel
+	BufferSize int
+}
+
+// StreamSSE subscribes to a Server-Sent Events endpoint. The connection is made through Do,
+// and is re-established with the Last-Event-ID header whenever it drops, until ctx is cancelled.
+// A 204 or a 4xx response other than 429 ends the stream.
+//
+// Note that the client timeout also bounds how long a single connection can stay open. Use
+// WithTimeout(0) for long-lived streams, otherwise the stream reconnects after each timeout.
+func (c *CustomHttpClient) StreamSSE(ctx context.Context, path string, config SSEConfig, opts ...RequestOption) *Stream[Event] {
+	if config.Backoff == nil {
+		config.Backoff = NewConstantBackoff(defaultSSERetry, 0)
+	}
+
+	stream := &Stream[Event]{items: make(chan Event, config.BufferSize)}
+
+	go func() {
+		defer close(stream.items)
+
+		state := &sseState{lastEventID: config.LastEventID}
+		failures := 0
+
+		for {
+			received, err := c.consumeSSE(ctx, path, state, opts, func(event Event) bool {
+				select {
+				case stream.items <- event:
+					return true
+				case <-ctx.Done():
+					return false
+				}
+			})
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			var fatal *fatalStreamError
+			if errors.As(err, &fatal) {
+				stream.err = fatal.err
+				return
+			}
+
+			if received {
+				failures = 0
+			} else {
+				failures++
+			}
+
+			if config.MaxReconnects > 0 && failures > config.MaxReconnects {
+				stream.err = errors.Wrap(err, "sse: too many failed reconnects")
+				return
+			}
+
+			wait := state.retry
+			if wait <= 0 {
+				wait = config.Backoff.Next(failures)
+			}
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+	}()
+
+	return stream
+}
+
+// sseState is carried across reconnects
+type sseState struct {
+	lastEventID string
+	retry       time.Duration // reconnection time sent by the server
+}
+
+// fatalStreamError marks an error that must not trigger a reconnect
+type fatalStreamError struct {
+	err error
+}
+
+func (e *fatalStreamError) Error() string {
+	return e.err.Error()
+}
+
+// consumeSSE makes one connection and emits events until the body ends. It reports whether
+// any event was received so the caller can reset its reconnect backoff.
+func (c *CustomHttpClient) consumeSSE(ctx context.Context, path string, state *sseState, opts []RequestOption, emit func(Event) bool) (bool, error) {
+	request, err := c.NewRequest(ctx, http.MethodGet, path, nil, opts...)
+	if err != nil {
+		return false, &fatalStreamError{err: err}
+	}
+
+	request.Header.Set("Accept", "text/event-stream")
+	request.Header.Set("Cache-Control", "no-cache")
+	if state.lastEventID != "" {
+		request.Header.Set("Last-Event-ID", state.lastEventID)
+	}
+
+	response, err := c.Do(request)
+	if err != nil {
+		if response != nil {
+			drainAndClose(response.Body)
+		}
+		return false, err
+	}
+	defer response.Body.Close()
+
+	switch {
+	case response.StatusCode == http.StatusNoContent:
+		return false, &fatalStreamError{err: errors.New("sse: server asked to stop reconnecting")}
+	case response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= http.StatusInternalServerError:
+		return false, fmt.Errorf("sse: unexpected status code %d", response.StatusCode)
+	case response.StatusCode != http.StatusOK:
+		return false, &fatalStreamError{err: fmt.Errorf("sse: unexpected status code %d", response.StatusCode)}
+	}
+
+	received := false
+	reader := newSSEReader(response.Body, state)
+	for {
+		event, err := reader.next()
+		if err != nil {
+			if err == io.EOF {
+				return received, errors.New("sse: stream closed by server")
+			}
+			return received, err
+		}
+
+		received = true
+		if !emit(event) {
+			return received, ctx.Err()
+		}
+	}
+}
+
+// sseReader parses the text/event-stream format
+type sseReader struct {
+	reader *bufio.Reader
+	state  *sseState
+}
+
+func newSSEReader(r io.Reader, state *sseState) *sseReader {
+	return &sseReader{reader: bufio.NewReader(r), state: state}
+}
+
+// next returns the next complete event. Events without data are skipped as the specification requires.
+func (r *sseReader) next() (Event, error) {
+	var data strings.Builder
+	hasData := false
+	event := Event{}
+
+	for {
+		line, err := r.reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return Event{}, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			if !hasData {
+				event = Event{}
+				continue
+			}
+
+			event.ID = r.state.lastEventID
+			event.Data = strings.TrimSuffix(data.String(), "\n")
+			if event.Event == "" {
+				event.Event = "message"
+			}
+			return event, nil
+		}
+
+		if strings.HasPrefix(line, ":") {
+			continue // comment, often used as keep-alive
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "event":
+			event.Event = value
+		case "data":
+			data.WriteString(value)
+			data.WriteString("\n")
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				r.state.lastEventID = value
+			}
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+				r.state.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
+
+// StreamNDJSON reads a newline delimited JSON response and decodes every line into T.
+// Blank lines are skipped. The stream ends when the body ends or ctx is cancelled.
+func StreamNDJSON[T any](ctx context.Context, c *CustomHttpClient, path string, opts ...RequestOption) *Stream[T] {
+	stream := &Stream[T]{items: make(chan T)}
+
+	go func() {
+		defer close(stream.items)
+
+		request, err := c.NewRequest(ctx, http.MethodGet, path, nil, opts...)
+		if err != nil {
+			stream.err = err
+			return
+		}
+		request.Header.Set("Accept", "application/x-ndjson")
+
+		response, err := c.Do(request)
+		if err != nil {
+			if response != nil {
+				drainAndClose(response.Body)
+			}
+			stream.err = err
+			return
+		}
+		defer response.Body.Close()
+
+		if response.StatusCode != http.StatusOK {
+			stream.err = fmt.Errorf("ndjson: unexpected status code %d", response.StatusCode)
+			return
+		}
+
+		reader := bufio.NewReader(response.Body)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(strings.TrimSpace(string(line))) > 0 {
+				var item T
+				if decodeErr := json.Unmarshal(line, &item); decodeErr != nil {
+					stream.err = errors.Wrap(decodeErr, "ndjson: failed to decode line")
+					return
+				}
+
+				select {
+				case stream.items <- item:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			if err != nil {
+				if err != io.EOF && ctx.Err() == nil {
+					stream.err = err
+				}
+				return
+			}
+		}
+	}()
+
+	return stream
+}
@@ -0,0 +1,118 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSEReaderParsesEvents(t *testing.T) {
+	body := ": keep-alive\n\n" +
+		"id: 1\nevent: status\ndata: {\"status\":\"PENDING\"}\n\n" +
+		"retry: 1500\n\n" +
+		"data: line one\r\ndata:line two\r\n\r\n" +
+		"id: 3\ndata: incomplete"
+
+	state := &sseState{}
+	reader := newSSEReader(strings.NewReader(body), state)
+
+	event, err := reader.next()
+	require.NoError(t, err)
+	assert.Equal(t, Event{ID: "1", Event: "status", Data: `{"status":"PENDING"}`}, event)
+
+	event, err = reader.next()
+	require.NoError(t, err)
+	assert.Equal(t, Event{ID: "1", Event: "message", Data: "line one\nline two"}, event)
+	assert.Equal(t, 1500*time.Millisecond, state.retry)
+
+	_, err = reader.next()
+	require.Error(t, err, "an event without the trailing blank line is discarded")
+}
+
+func TestStreamSSEReconnectsWithLastEventID(t *testing.T) {
+	var connections atomic.Int32
+
+	dummyHandler := func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "text/event-stream", r.Header.Get("Accept"))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		switch connections.Add(1) {
+		case 1:
+			assert.Equal(t, "", r.Header.Get("Last-Event-ID"))
+			fmt.Fprint(w, "retry: 1\n\nid: 1\ndata: first\n\nid: 2\ndata: second\n\n")
+		case 2:
+			assert.Equal(t, "2", r.Header.Get("Last-Event-ID"))
+			fmt.Fprint(w, "id: 3\ndata: third\n\n")
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(dummyHandler))
+	defer server.Close()
+
+	client := NewClient(WithTimeout(time.Second), WithBaseURL(server.URL))
+	stream := client.StreamSSE(context.Background(), "/events", SSEConfig{})
+
+	var data []string
+	for event := range stream.C() {
+		data = append(data, event.ID+":"+event.Data)
+	}
+
+	assert.Equal(t, []string{"1:first", "2:second", "3:third"}, data)
+	require.Error(t, stream.Err())
+	assert.Equal(t, int32(3), connections.Load())
+}
+
+func TestStreamSSEStopsOnContextCancel(t *testing.T) {
+	dummyHandler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: tick\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(dummyHandler))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client := NewClient(WithTimeout(0), WithBaseURL(server.URL))
+	stream := client.StreamSSE(ctx, "/events", SSEConfig{Backoff: NewConstantBackoff(time.Millisecond, 0)})
+
+	event := <-stream.C()
+	assert.Equal(t, "tick", event.Data)
+
+	cancel()
+	for range stream.C() {
+	}
+	assert.NoError(t, stream.Err())
+}
+
+func TestStreamNDJSON(t *testing.T) {
+	dummyHandler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		fmt.Fprint(w, "{\"id\":\"a\",\"amount\":1}\n\n{\"id\":\"b\",\"amount\":2}\n")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(dummyHandler))
+	defer server.Close()
+
+	client := NewClient(WithTimeout(time.Second), WithBaseURL(server.URL))
+	stream := StreamNDJSON[transferResponse](context.Background(), client, "/export")
+
+	var items []transferResponse
+	for item := range stream.C() {
+		items = append(items, item)
+	}
+
+	require.NoError(t, stream.Err())
+	assert.Equal(t, []transferResponse{{ID: "a", Amount: 1}, {ID: "b", Amount: 2}}, items)
+}
@@ -0,0 +1,229 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timings is the connection level timing breakdown of a single attempt
+type Timings struct {
+	Attempt         int           // zero based attempt number within Do
+	DNSLookup       time.Duration // zero when the connection was reused or the host is an IP
+	TCPConnect      time.Duration
+	TLSHandshake    time.Duration
+	TimeToFirstByte time.Duration // from request written to first response byte, i.e. server processing
+	BodyTransfer    time.Duration // from first response byte until the body is fully read or closed
+	Total           time.Duration
+	ConnReused      bool
+	ConnWasIdle     bool
+	ConnIdleTime    time.Duration
+	RemoteAddr      string
+	Err             error // transport error of the attempt, if any
+}
+
+// String formats the timings for logging
+func (t Timings) String() string {
+	return fmt.Sprintf("attempt=%d dns=%s connect=%s tls=%s ttfb=%s transfer=%s total=%s reused=%t remote=%s",
+		t.Attempt, t.DNSLookup, t.TCPConnect, t.TLSHandshake, t.TimeToFirstByte, t.BodyTransfer, t.Total, t.ConnReused, t.RemoteAddr)
+}
+
+// TraceCallback receives the timings of every attempt once it completes, that is when the
+// response body is fully read or closed, or right away when the attempt failed. Use it to feed
+// logs, metrics or tracing spans.
+type TraceCallback func(request *http.Request, timings Timings)
+
+// LogTimings returns a TraceCallback writing one line per attempt to logFunc, e.g. the Log
+// method of a pakakeh logger. The URL is logged without its user info.
+func LogTimings(logFunc func(message string)) TraceCallback {
+	return func(request *http.Request, timings Timings) {
+		message := fmt.Sprintf("%s %s %s", request.Method, request.URL.Redacted(), timings.String())
+		if timings.Err != nil {
+			message += " error=" + timings.Err.Error()
+		}
+		logFunc(message)
+	}
+}
+
+// MultiTrace returns a TraceCallback calling every callback in order, e.g. a log and a
+// tracing callback for the same client. Nil callbacks are skipped.
+func MultiTrace(callbacks ...TraceCallback) TraceCallback {
+	return func(request *http.Request, timings Timings) {
+		for _, callback := range callbacks {
+			if callback != nil {
+				callback(request, timings)
+			}
+		}
+	}
+}
+
+type timingsKey struct{}
+
+// TimingsFromResponse returns the timings of the attempt that produced response. It is only
+// available when the client is created with WithHTTPTrace, and BodyTransfer and Total are
+// only final after the body is read or closed.
+func TimingsFromResponse(response *http.Response) (Timings, bool) {
+	if response == nil || response.Request == nil {
+		return Timings{}, false
+	}
+
+	tracer, ok := response.Request.Context().Value(timingsKey{}).(*attemptTracer)
+	if !ok {
+		return Timings{}, false
+	}
+
+	return tracer.snapshot(), true
+}
+
+// attemptTracer collects httptrace events of one attempt
+type attemptTracer struct {
+	mu       sync.Mutex
+	timings  Timings
+	start    time.Time
+	dnsStart time.Time
+	connect  time.Time
+	tlsStart time.Time
+	wrote    time.Time
+	first    time.Time
+	finished bool
+	callback TraceCallback
+	request  *http.Request
+}
+
+// traceAttempt returns a copy of request carrying httptrace hooks for the given attempt
+func traceAttempt(request *http.Request, attempt int, callback TraceCallback) (*http.Request, *attemptTracer) {
+	t := &attemptTracer{
+		timings:  Timings{Attempt: attempt},
+		start:    time.Now(),
+		callback: callback,
+	}
+
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.record(func() { t.dnsStart = time.Now() })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.record(func() { t.timings.DNSLookup = time.Since(t.dnsStart) })
+		},
+		ConnectStart: func(string, string) {
+			t.record(func() {
+				if t.connect.IsZero() {
+					t.connect = time.Now()
+				}
+			})
+		},
+		ConnectDone: func(_, _ string, err error) {
+			t.record(func() {
+				if err == nil {
+					t.timings.TCPConnect = time.Since(t.connect)
+				}
+			})
+		},
+		TLSHandshakeStart: func() {
+			t.record(func() { t.tlsStart = time.Now() })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.record(func() { t.timings.TLSHandshake = time.Since(t.tlsStart) })
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.record(func() {
+				t.timings.ConnReused = info.Reused
+				t.timings.ConnWasIdle = info.WasIdle
+				t.timings.ConnIdleTime = info.IdleTime
+				if info.Conn != nil {
+					t.timings.RemoteAddr = info.Conn.RemoteAddr().String()
+				}
+			})
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.record(func() { t.wrote = time.Now() })
+		},
+		GotFirstResponseByte: func() {
+			t.record(func() {
+				t.first = time.Now()
+				if !t.wrote.IsZero() {
+					t.timings.TimeToFirstByte = t.first.Sub(t.wrote)
+				}
+			})
+		},
+	}
+
+	ctx := context.WithValue(request.Context(), timingsKey{}, t)
+	traced := request.WithContext(httptrace.WithClientTrace(ctx, trace))
+	t.request = traced
+
+	return traced, t
+}
+
+func (t *attemptTracer) record(fn func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fn()
+}
+
+func (t *attemptTracer) snapshot() Timings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.timings
+}
+
+// done attaches the tracer to the attempt outcome. A response body is wrapped so that the
+// timings are completed when it is read or closed.
+func (t *attemptTracer) done(response *http.Response, err error) {
+	if err != nil || response == nil || response.Body == nil {
+		t.finish(err)
+		return
+	}
+
+	response.Body = &tracedBody{ReadCloser: response.Body, tracer: t}
+}
+
+func (t *attemptTracer) finish(err error) {
+	t.mu.Lock()
+	if t.finished {
+		t.mu.Unlock()
+		return
+	}
+
+	t.finished = true
+	now := time.Now()
+	if !t.first.IsZero() {
+		t.timings.BodyTransfer = now.Sub(t.first)
+	}
+	t.timings.Total = now.Sub(t.start)
+	t.timings.Err = err
+	timings := t.timings
+	t.mu.Unlock()
+
+	if t.callback != nil {
+		t.callback(t.request, timings)
+	}
+}
+
+// tracedBody completes the attempt timings at EOF or Close
+type tracedBody struct {
+	io.ReadCloser
+	tracer *attemptTracer
+}
+
+func (b *tracedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	switch {
+	case err == io.EOF:
+		b.tracer.finish(nil)
+	case err != nil:
+		b.tracer.finish(err)
+	}
+	return n, err
+}
+
+func (b *tracedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.tracer.finish(nil)
+	return err
+}
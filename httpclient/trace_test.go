@@ -0,0 +1,98 @@
+package httpclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPClientTraceTimings(t *testing.T) {
+	dummyHandler := func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{ "response": "ok gas" }`))
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(dummyHandler))
+	defer server.Close()
+
+	mu := &sync.Mutex{}
+	var collected []Timings
+	client := NewClient(WithTimeout(time.Second), WithHTTPTrace(func(request *http.Request, timings Timings) {
+		mu.Lock()
+		defer mu.Unlock()
+		collected = append(collected, timings)
+	}))
+
+	for i := 0; i < 2; i++ {
+		response, err := client.Get(server.URL, http.Header{})
+		require.NoError(t, err)
+
+		_, err = io.ReadAll(response.Body)
+		require.NoError(t, err)
+		response.Body.Close()
+
+		timings, ok := TimingsFromResponse(response)
+		require.True(t, ok)
+		assert.GreaterOrEqual(t, timings.TimeToFirstByte, 5*time.Millisecond)
+		assert.GreaterOrEqual(t, timings.Total, timings.TimeToFirstByte)
+		assert.NotEmpty(t, timings.RemoteAddr)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, collected, 2)
+	assert.False(t, collected[0].ConnReused)
+	assert.Greater(t, collected[0].TCPConnect, time.Duration(0))
+	assert.True(t, collected[1].ConnReused)
+	assert.Equal(t, time.Duration(0), collected[1].TCPConnect)
+}
+
+func TestHTTPClientTraceFailedAttempts(t *testing.T) {
+	var collected []Timings
+	client := NewClient(
+		WithTimeout(10*time.Millisecond),
+		WithRetryCount(1),
+		WithHTTPTrace(func(request *http.Request, timings Timings) {
+			collected = append(collected, timings)
+		}),
+	)
+
+	_, err := client.Get("http://127.0.0.1:1", http.Header{})
+	require.Error(t, err)
+
+	require.Len(t, collected, 2)
+	assert.Equal(t, 0, collected[0].Attempt)
+	assert.Equal(t, 1, collected[1].Attempt)
+	assert.Error(t, collected[1].Err)
+}
+
+func TestLogTimingsAndMultiTrace(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var logged []string
+	var traced int
+	client := NewClient(WithTimeout(time.Second), WithHTTPTrace(MultiTrace(
+		LogTimings(func(message string) { logged = append(logged, message) }),
+		nil,
+		func(*http.Request, Timings) { traced++ },
+	)))
+
+	response, err := client.Get(server.URL+"/v1/status", http.Header{})
+	require.NoError(t, err)
+	response.Body.Close()
+
+	require.Len(t, logged, 1)
+	assert.Contains(t, logged[0], "GET "+server.URL+"/v1/status attempt=0 ")
+	assert.Contains(t, logged[0], "reused=false")
+	assert.Equal(t, 1, traced)
+}
@@ -0,0 +1,238 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TransportConfig holds the connection level settings used to build the underlying
+// *http.Transport. Zero values fall back to the values of DefaultTransportConfig.
+type TransportConfig struct {
+	MaxIdleConns           int           // maximum idle connections across all hosts
+	MaxIdleConnsPerHost    int           // maximum idle connections kept per host
+	MaxConnsPerHost        int           // 0 means no limit
+	IdleConnTimeout        time.Duration // how long an idle connection stays in the pool
+	DialTimeout            time.Duration // TCP connect timeout
+	KeepAlive              time.Duration // TCP keep-alive period
+	TLSHandshakeTimeout    time.Duration
+	ResponseHeaderTimeout  time.Duration // 0 means no limit, the client timeout still applies
+	ExpectContinueTimeout  time.Duration
+	MaxResponseHeaderBytes int64 // 0 uses the net/http default of 1MB
+	DisableKeepAlives      bool
+	DisableHTTP2           bool
+	TLSClientConfig        *tls.Config
+
+	// Proxy is used for every request unless a HostProxies rule matches the request host.
+	// Supported schemes are http, https and socks5.
+	Proxy *url.URL
+	// HostProxies maps a request host (without port) to its proxy. A key starting with
+	// "." matches the domain and all of its subdomains, e.g. ".bank.co.id". An exact host
+	// wins over a domain, and the longest matching domain wins over the shorter ones.
+	HostProxies map[string]*url.URL
+	// NoProxy lists hosts that are always dialed directly, using the same matching rule as HostProxies.
+	NoProxy []string
+	// ProxyFromEnvironment uses HTTP_PROXY, HTTPS_PROXY and NO_PROXY when no other rule matches.
+	// It is on in DefaultTransportConfig, like http.DefaultTransport.
+	ProxyFromEnvironment bool
+}
+
+// DefaultTransportConfig returns production friendly defaults for TransportConfig
+func DefaultTransportConfig() TransportConfig {
+	return TransportConfig{
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   10,
+		IdleConnTimeout:       90 * time.Second,
+		DialTimeout:           30 * time.Second,
+		KeepAlive:             30 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		ProxyFromEnvironment:  true,
+	}
+}
+
+// NewTransport builds an *http.Transport from the given config
+func NewTransport(cfg TransportConfig) *http.Transport {
+	cfg = cfg.withDefaults()
+
+	dialer := &net.Dialer{
+		Timeout:   cfg.DialTimeout,
+		KeepAlive: cfg.KeepAlive,
+	}
+
+	transport := &http.Transport{
+		Proxy:                  cfg.proxyFunc(),
+		DialContext:            dialer.DialContext,
+		MaxIdleConns:           cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:    cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:        cfg.MaxConnsPerHost,
+		IdleConnTimeout:        cfg.IdleConnTimeout,
+		TLSHandshakeTimeout:    cfg.TLSHandshakeTimeout,
+		ResponseHeaderTimeout:  cfg.ResponseHeaderTimeout,
+		ExpectContinueTimeout:  cfg.ExpectContinueTimeout,
+		MaxResponseHeaderBytes: cfg.MaxResponseHeaderBytes,
+		DisableKeepAlives:      cfg.DisableKeepAlives,
+		ForceAttemptHTTP2:      !cfg.DisableHTTP2,
+		TLSClientConfig:        cfg.TLSClientConfig,
+	}
+
+	if cfg.DisableHTTP2 {
+		// a non-nil empty map disables the automatic HTTP/2 upgrade
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	return transport
+}
+
+func (cfg TransportConfig) withDefaults() TransportConfig {
+	def := DefaultTransportConfig()
+
+	if cfg.MaxIdleConns == 0 {
+		cfg.MaxIdleConns = def.MaxIdleConns
+	}
+	if cfg.MaxIdleConnsPerHost == 0 {
+		cfg.MaxIdleConnsPerHost = def.MaxIdleConnsPerHost
+	}
+	if cfg.IdleConnTimeout == 0 {
+		cfg.IdleConnTimeout = def.IdleConnTimeout
+	}
+	if cfg.DialTimeout == 0 {
+		cfg.DialTimeout = def.DialTimeout
+	}
+	if cfg.KeepAlive == 0 {
+		cfg.KeepAlive = def.KeepAlive
+	}
+	if cfg.TLSHandshakeTimeout == 0 {
+		cfg.TLSHandshakeTimeout = def.TLSHandshakeTimeout
+	}
+	if cfg.ExpectContinueTimeout == 0 {
+		cfg.ExpectContinueTimeout = def.ExpectContinueTimeout
+	}
+
+	return cfg
+}
+
+// merge copies the non-zero fields of other into cfg. HostProxies are added to the existing
+// rules and NoProxy hosts are appended, so options can be given in any order.
+func (cfg *TransportConfig) merge(other TransportConfig) {
+	if other.MaxIdleConns != 0 {
+		cfg.MaxIdleConns = other.MaxIdleConns
+	}
+	if other.MaxIdleConnsPerHost != 0 {
+		cfg.MaxIdleConnsPerHost = other.MaxIdleConnsPerHost
+	}
+	if other.MaxConnsPerHost != 0 {
+		cfg.MaxConnsPerHost = other.MaxConnsPerHost
+	}
+	if other.IdleConnTimeout != 0 {
+		cfg.IdleConnTimeout = other.IdleConnTimeout
+	}
+	if other.DialTimeout != 0 {
+		cfg.DialTimeout = other.DialTimeout
+	}
+	if other.KeepAlive != 0 {
+		cfg.KeepAlive = other.KeepAlive
+	}
+	if other.TLSHandshakeTimeout != 0 {
+		cfg.TLSHandshakeTimeout = other.TLSHandshakeTimeout
+	}
+	if other.ResponseHeaderTimeout != 0 {
+		cfg.ResponseHeaderTimeout = other.ResponseHeaderTimeout
+	}
+	if other.ExpectContinueTimeout != 0 {
+		cfg.ExpectContinueTimeout = other.ExpectContinueTimeout
+	}
+	if other.MaxResponseHeaderBytes != 0 {
+		cfg.MaxResponseHeaderBytes = other.MaxResponseHeaderBytes
+	}
+	if other.DisableKeepAlives {
+		cfg.DisableKeepAlives = true
+	}
+	if other.DisableHTTP2 {
+		cfg.DisableHTTP2 = true
+	}
+	if other.TLSClientConfig != nil {
+		cfg.TLSClientConfig = other.TLSClientConfig
+	}
+	if other.Proxy != nil {
+		cfg.Proxy = other.Proxy
+	}
+	if len(other.HostProxies) > 0 {
+		if cfg.HostProxies == nil {
+			cfg.HostProxies = make(map[string]*url.URL, len(other.HostProxies))
+		}
+		for host, proxy := range other.HostProxies {
+			cfg.HostProxies[host] = proxy
+		}
+	}
+	cfg.NoProxy = append(cfg.NoProxy, other.NoProxy...)
+}
+
+// proxyFunc resolves the proxy for a request, checking NoProxy, HostProxies, Proxy
+// and finally the environment, in that order
+func (cfg TransportConfig) proxyFunc() func(*http.Request) (*url.URL, error) {
+	if cfg.Proxy == nil && len(cfg.HostProxies) == 0 && !cfg.ProxyFromEnvironment {
+		return nil
+	}
+
+	return func(req *http.Request) (*url.URL, error) {
+		host := strings.ToLower(req.URL.Hostname())
+
+		for _, pattern := range cfg.NoProxy {
+			if matchHost(pattern, host) {
+				return nil, nil
+			}
+		}
+
+		if proxy, ok := hostProxy(cfg.HostProxies, host); ok {
+			return proxy, nil
+		}
+
+		if cfg.Proxy != nil {
+			return cfg.Proxy, nil
+		}
+
+		if cfg.ProxyFromEnvironment {
+			return http.ProxyFromEnvironment(req)
+		}
+
+		return nil, nil
+	}
+}
+
+// hostProxy returns the proxy of the most specific HostProxies rule matching host: the exact
+// host first, then the longest matching domain
+func hostProxy(rules map[string]*url.URL, host string) (*url.URL, bool) {
+	var (
+		proxy   *url.URL
+		longest = -1
+	)
+
+	for pattern, candidate := range rules {
+		if !matchHost(pattern, host) {
+			continue
+		}
+		if !strings.HasPrefix(pattern, ".") {
+			return candidate, true
+		}
+		if len(pattern) > longest {
+			proxy, longest = candidate, len(pattern)
+		}
+	}
+
+	return proxy, longest >= 0
+}
+
+// matchHost reports whether host matches pattern. A pattern with a leading dot matches
+// the domain itself and all of its subdomains. The match is case insensitive.
+func matchHost(pattern, host string) bool {
+	pattern, host = strings.ToLower(pattern), strings.ToLower(host)
+	if strings.HasPrefix(pattern, ".") {
+		return host == pattern[1:] || strings.HasSuffix(host, pattern)
+	}
+
+	return host == pattern
+}
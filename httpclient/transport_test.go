@@ -0,0 +1,159 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTransportAppliesDefaults(t *testing.T) {
+	transport := NewTransport(TransportConfig{MaxIdleConnsPerHost: 50})
+
+	assert.Equal(t, 50, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, 100, transport.MaxIdleConns)
+	assert.Equal(t, 90*time.Second, transport.IdleConnTimeout)
+	assert.True(t, transport.ForceAttemptHTTP2)
+	assert.Nil(t, transport.Proxy)
+}
+
+func TestNewTransportDisableHTTP2(t *testing.T) {
+	transport := NewTransport(TransportConfig{DisableHTTP2: true})
+
+	assert.False(t, transport.ForceAttemptHTTP2)
+	assert.NotNil(t, transport.TLSNextProto)
+}
+
+func TestTransportProxyRules(t *testing.T) {
+	defaultProxy, _ := url.Parse("http://proxy.local:3128")
+	bankProxy, _ := url.Parse("socks5://10.0.0.1:1080")
+
+	transport := NewTransport(TransportConfig{
+		Proxy:       defaultProxy,
+		HostProxies: map[string]*url.URL{".bank.co.id": bankProxy},
+		NoProxy:     []string{"internal.svc"},
+	})
+	require.NotNil(t, transport.Proxy)
+
+	cases := map[string]*url.URL{
+		"https://api.bank.co.id/v1":  bankProxy,
+		"https://bank.co.id/v1":      bankProxy,
+		"http://internal.svc/health": nil,
+		"https://example.com":        defaultProxy,
+	}
+
+	for rawURL, expected := range cases {
+		req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+		require.NoError(t, err)
+
+		proxy, err := transport.Proxy(req)
+		require.NoError(t, err)
+		assert.Equal(t, expected, proxy, rawURL)
+	}
+}
+
+func TestTransportProxyRulesMostSpecificWins(t *testing.T) {
+	bankProxy, _ := url.Parse("socks5://10.0.0.1:1080")
+	apiProxy, _ := url.Parse("socks5://10.0.0.2:1080")
+	gatewayProxy, _ := url.Parse("http://10.0.0.3:3128")
+
+	transport := NewTransport(TransportConfig{
+		HostProxies: map[string]*url.URL{
+			".co.id":              gatewayProxy,
+			".bank.co.id":         bankProxy,
+			".api.bank.co.id":     apiProxy,
+			"Payment.Bank.co.id":  gatewayProxy,
+			".payment.bank.co.id": apiProxy,
+		},
+	})
+
+	cases := map[string]*url.URL{
+		"https://bank.co.id":             bankProxy,
+		"https://web.bank.co.id":         bankProxy,
+		"https://api.bank.co.id":         apiProxy,
+		"https://v2.api.bank.co.id":      apiProxy,
+		"https://payment.bank.co.id":     gatewayProxy,
+		"https://eu.payment.bank.co.id":  apiProxy,
+		"https://merchant.co.id":         gatewayProxy,
+		"https://merchant.example.co.uk": nil,
+	}
+
+	// map iteration order is random, so repeat to catch an order dependent match
+	for i := 0; i < 20; i++ {
+		for rawURL, expected := range cases {
+			req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+			require.NoError(t, err)
+
+			proxy, err := transport.Proxy(req)
+			require.NoError(t, err)
+			assert.Equal(t, expected, proxy, rawURL)
+		}
+	}
+}
+
+func TestHTTPClientWithTransportConfig(t *testing.T) {
+	proxy, _ := url.Parse("http://proxy.local:3128")
+	client := NewClient(
+		WithTransportConfig(TransportConfig{MaxIdleConnsPerHost: 32}),
+		WithHostProxy("partner.com", proxy),
+	)
+
+	httpClient, ok := client.client.(*http.Client)
+	require.True(t, ok)
+
+	transport, ok := httpClient.Transport.(*http.Transport)
+	require.True(t, ok)
+	assert.Equal(t, 32, transport.MaxIdleConnsPerHost)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	response, err := client.Get(server.URL, http.Header{})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+}
+
+func TestHTTPClientTransportOptionsAnyOrder(t *testing.T) {
+	proxy, _ := url.Parse("http://proxy.local:3128")
+
+	client := NewClient(
+		WithMaxResponseHeaderSize(4096),
+		WithHostProxy("partner.com", proxy),
+		WithTransportConfig(TransportConfig{MaxIdleConnsPerHost: 32, NoProxy: []string{"internal.svc"}}),
+	)
+
+	httpClient, ok := client.client.(*http.Client)
+	require.True(t, ok)
+	transport, ok := httpClient.Transport.(*http.Transport)
+	require.True(t, ok)
+
+	assert.Equal(t, 32, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, int64(4096), transport.MaxResponseHeaderBytes)
+	assert.True(t, client.transportConfig.ProxyFromEnvironment)
+	require.NotNil(t, transport.Proxy)
+
+	cases := map[string]string{
+		"https://partner.com/v1":      "http://proxy.local:3128",
+		"https://internal.svc/health": "",
+	}
+
+	for rawURL, expected := range cases {
+		req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+		require.NoError(t, err)
+
+		got, err := transport.Proxy(req)
+		require.NoError(t, err)
+		if expected == "" {
+			assert.Nil(t, got, rawURL)
+			continue
+		}
+		require.NotNil(t, got, rawURL)
+		assert.Equal(t, expected, got.String(), rawURL)
+	}
+}
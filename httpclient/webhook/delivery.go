@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"net/http"
+	"time"
+)
+
+// Status is the state of a delivery
+type Status string
+
+const (
+	// StatusPending deliveries are sent when NextAttemptAt is reached
+	StatusPending Status = "pending"
+	// StatusSucceeded deliveries got a 2xx response
+	StatusSucceeded Status = "succeeded"
+	// StatusFailed deliveries used all of their attempts, use Redeliver to send them again
+	StatusFailed Status = "failed"
+)
+
+// Delivery is a payload to be sent to a callback URL, together with its attempt history
+type Delivery struct {
+	ID      string      `json:"id"`
+	URL     string      `json:"url"`
+	Event   string      `json:"event"`
+	Payload []byte      `json:"payload"`
+	Header  http.Header `json:"header,omitempty"` // extra headers sent on every attempt
+
+	Status        Status    `json:"status"`
+	Retry         int       `json:"retry"` // attempts made since the delivery was enqueued or redelivered
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	Attempts      []Attempt `json:"attempts"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Attempt is the outcome of a single attempt to send a delivery
+type Attempt struct {
+	Number     int           `json:"number"`
+	StartedAt  time.Time     `json:"started_at"`
+	StatusCode int           `json:"status_code,omitempty"` // 0 when no response was received
+	Latency    time.Duration `json:"latency"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// Succeeded reports whether the attempt got a 2xx response
+func (a Attempt) Succeeded() bool {
+	return a.Error == "" && a.StatusCode >= 200 && a.StatusCode < 300
+}
+
+// clone returns a copy that does not share the attempts and header with d
+func (d *Delivery) clone() *Delivery {
+	clone := *d
+	clone.Header = d.Header.Clone()
+	clone.Attempts = append([]Attempt(nil), d.Attempts...)
+	return &clone
+}
@@ -0,0 +1,342 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/PCS-Indonesia/pakakeh/httpclient"
+)
+
+const (
+	defaultMaxAttempts  = 10
+	defaultTimeout      = 10 * time.Second
+	defaultConcurrency  = 4
+	defaultPollInterval = time.Second
+	maxDrainBytes       = 64 << 10
+)
+
+var logger = log.New(os.Stdout, "webhook:", log.LstdFlags)
+
+// ErrDeliveryInFlight is returned by Redeliver while the delivery is being sent
+var ErrDeliveryInFlight = errors.New("webhook delivery is being sent")
+
+// Config configures a Dispatcher
+type Config struct {
+	// Client sends the deliveries. It should not retry by itself, the dispatcher does.
+	// Default is httpclient.NewClient with Timeout.
+	Client httpclient.DoReq
+	// Secret signs every payload, SecretFunc overrides it, e.g. for a secret per merchant
+	Secret     []byte
+	SecretFunc func(delivery *Delivery) ([]byte, error)
+	// Backoff is the wait before the next attempt of a failed delivery,
+	// default is exponential from 10 seconds up to 1 hour
+	Backoff httpclient.Backoff
+	// MaxAttempts before a delivery is marked StatusFailed, default is 10
+	MaxAttempts int
+	// Timeout of a single attempt, default is 10 seconds
+	Timeout time.Duration
+	// Concurrency is the maximum number of deliveries sent at once, default is 4
+	Concurrency int
+	// PollInterval is how often the store is checked for due deliveries, default is 1 second
+	PollInterval    time.Duration
+	SignatureHeader string // default DefaultSignatureHeader
+	TimestampHeader string // default DefaultTimestampHeader
+	// OnAttempt is called after every attempt, e.g. for metrics
+	OnAttempt func(delivery Delivery, attempt Attempt)
+	// OnError is called when the store fails in the background, default logs the error
+	OnError func(err error)
+}
+
+// Dispatcher sends deliveries kept in a Store, retrying failed ones with backoff.
+// Only one Dispatcher should run per Store.
+type Dispatcher struct {
+	store  Store
+	config Config
+	now    func() time.Time
+	wake   chan struct{}
+
+	mu       sync.Mutex
+	inFlight map[string]bool
+	wg       sync.WaitGroup
+}
+
+// NewDispatcher returns a Dispatcher for store, call Run to start sending
+func NewDispatcher(store Store, config Config) (*Dispatcher, error) {
+	if store == nil {
+		return nil, errors.New("webhook store is required")
+	}
+	if len(config.Secret) == 0 && config.SecretFunc == nil {
+		return nil, errors.New("webhook secret is required")
+	}
+
+	if config.MaxAttempts < 1 {
+		config.MaxAttempts = defaultMaxAttempts
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+	if config.Concurrency < 1 {
+		config.Concurrency = defaultConcurrency
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaultPollInterval
+	}
+	if config.Backoff == nil {
+		config.Backoff = httpclient.NewExponentialBackoff(10*time.Second, time.Hour, 2, time.Second)
+	}
+	if config.Client == nil {
+		config.Client = httpclient.NewClient(httpclient.WithTimeout(config.Timeout))
+	}
+	if config.SignatureHeader == "" {
+		config.SignatureHeader = DefaultSignatureHeader
+	}
+	if config.TimestampHeader == "" {
+		config.TimestampHeader = DefaultTimestampHeader
+	}
+	if config.OnError == nil {
+		config.OnError = func(err error) {
+			logger.Printf("%v", err)
+		}
+	}
+
+	return &Dispatcher{
+		store:    store,
+		config:   config,
+		now:      time.Now,
+		wake:     make(chan struct{}, 1),
+		inFlight: make(map[string]bool),
+	}, nil
+}
+
+// Enqueue saves delivery as pending and wakes up the dispatcher. URL, Event and Payload
+// are set by the caller, an empty ID is generated. The saved delivery is returned.
+func (d *Dispatcher) Enqueue(ctx context.Context, delivery Delivery) (*Delivery, error) {
+	if delivery.URL == "" {
+		return nil, errors.New("webhook delivery URL is required")
+	}
+	if delivery.ID == "" {
+		delivery.ID = httpclient.NewCorrelationID()
+	}
+
+	now := d.now()
+	delivery.Status = StatusPending
+	delivery.Retry = 0
+	delivery.NextAttemptAt = now
+	delivery.Attempts = nil
+	delivery.CreatedAt = now
+	delivery.UpdatedAt = now
+
+	if err := d.store.Save(ctx, &delivery); err != nil {
+		return nil, errors.Wrap(err, "enqueue webhook delivery")
+	}
+
+	d.notify()
+	return &delivery, nil
+}
+
+// Redeliver sends a delivery again, whatever its status. The attempt history is kept
+// and the delivery gets MaxAttempts new attempts.
+func (d *Dispatcher) Redeliver(ctx context.Context, id string) (*Delivery, error) {
+	d.mu.Lock()
+	if d.inFlight[id] {
+		d.mu.Unlock()
+		return nil, ErrDeliveryInFlight
+	}
+	// hold the delivery so it is not picked up while it is updated
+	d.inFlight[id] = true
+	d.mu.Unlock()
+
+	defer func() {
+		d.release(id)
+		d.notify()
+	}()
+
+	delivery, err := d.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := d.now()
+	delivery.Status = StatusPending
+	delivery.Retry = 0
+	delivery.NextAttemptAt = now
+	delivery.UpdatedAt = now
+
+	if err := d.store.Save(ctx, delivery); err != nil {
+		return nil, errors.Wrap(err, "redeliver webhook delivery")
+	}
+
+	return delivery, nil
+}
+
+// Get returns the delivery with its attempt history
+func (d *Dispatcher) Get(ctx context.Context, id string) (*Delivery, error) {
+	return d.store.Get(ctx, id)
+}
+
+// Run sends due deliveries until ctx is done. Pending deliveries left in the store by a
+// previous run are picked up. Attempts in flight are completed before Run returns ctx.Err().
+func (d *Dispatcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.dispatchDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			d.wg.Wait()
+			return ctx.Err()
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+func (d *Dispatcher) dispatchDue(ctx context.Context) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	d.mu.Lock()
+	free := d.config.Concurrency - len(d.inFlight)
+	d.mu.Unlock()
+	if free <= 0 {
+		return
+	}
+
+	// in flight deliveries are still pending in the store, ask enough to fill every free slot
+	due, err := d.store.Due(ctx, d.now(), d.config.Concurrency)
+	if err != nil {
+		// the deliveries returned with the error are still sent
+		d.config.OnError(errors.Wrap(err, "load due webhook deliveries"))
+	}
+
+	for _, delivery := range due {
+		d.mu.Lock()
+		if len(d.inFlight) >= d.config.Concurrency {
+			d.mu.Unlock()
+			return
+		}
+		if d.inFlight[delivery.ID] {
+			d.mu.Unlock()
+			continue
+		}
+		d.inFlight[delivery.ID] = true
+		d.mu.Unlock()
+
+		d.wg.Add(1)
+		go func(delivery *Delivery) {
+			defer d.wg.Done()
+			// the store is updated before release, so the delivery is not picked up twice
+			defer d.notify()
+			defer d.release(delivery.ID)
+
+			d.attempt(context.WithoutCancel(ctx), delivery)
+		}(delivery)
+	}
+}
+
+// attempt sends delivery once and saves the outcome
+func (d *Dispatcher) attempt(ctx context.Context, delivery *Delivery) {
+	attempt := Attempt{Number: len(delivery.Attempts) + 1, StartedAt: d.now()}
+
+	start := time.Now()
+	statusCode, err := d.send(ctx, delivery)
+	attempt.Latency = time.Since(start)
+	attempt.StatusCode = statusCode
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+
+	now := d.now()
+	delivery.Attempts = append(delivery.Attempts, attempt)
+	delivery.Retry++
+	delivery.UpdatedAt = now
+
+	switch {
+	case attempt.Succeeded():
+		delivery.Status = StatusSucceeded
+	case delivery.Retry >= d.config.MaxAttempts:
+		delivery.Status = StatusFailed
+	default:
+		delivery.NextAttemptAt = now.Add(d.config.Backoff.Next(delivery.Retry - 1))
+	}
+
+	if err := d.store.Save(ctx, delivery); err != nil {
+		d.config.OnError(errors.Wrapf(err, "save webhook delivery %s", delivery.ID))
+	}
+
+	if d.config.OnAttempt != nil {
+		d.config.OnAttempt(*delivery.clone(), attempt)
+	}
+}
+
+// send posts the signed payload and returns the response status code
+func (d *Dispatcher) send(ctx context.Context, delivery *Delivery) (int, error) {
+	secret := d.config.Secret
+	if d.config.SecretFunc != nil {
+		var err error
+		if secret, err = d.config.SecretFunc(delivery); err != nil {
+			return 0, errors.Wrap(err, "webhook secret")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.config.Timeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, errors.Wrap(err, "build webhook request")
+	}
+
+	for key, values := range delivery.Header {
+		for _, value := range values {
+			request.Header.Add(key, value)
+		}
+	}
+	if request.Header.Get("Content-Type") == "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	timestamp := d.now().Unix()
+	request.Header.Set(HeaderDeliveryID, delivery.ID)
+	request.Header.Set(HeaderEvent, delivery.Event)
+	request.Header.Set(d.config.TimestampHeader, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(d.config.SignatureHeader, Sign(secret, timestamp, delivery.Payload))
+
+	response, err := d.config.Client.Do(request)
+	// the body is not kept, drain it so the connection can be reused, also when the
+	// retries gave up with the last response
+	if response != nil {
+		_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, maxDrainBytes))
+		response.Body.Close()
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return response.StatusCode, nil
+}
+
+func (d *Dispatcher) release(id string) {
+	d.mu.Lock()
+	delete(d.inFlight, id)
+	d.mu.Unlock()
+}
+
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
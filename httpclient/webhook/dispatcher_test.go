@@ -0,0 +1,215 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/PCS-Indonesia/pakakeh/httpclient"
+)
+
+var testSecret = []byte("merchant-secret")
+
+func runDispatcher(t *testing.T, dispatcher *Dispatcher) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- dispatcher.Run(ctx) }()
+
+	return func() {
+		cancel()
+		assert.ErrorIs(t, <-done, context.Canceled)
+	}
+}
+
+func waitStatus(t *testing.T, dispatcher *Dispatcher, id string, status Status) *Delivery {
+	var delivery *Delivery
+	require.Eventually(t, func() bool {
+		var err error
+		delivery, err = dispatcher.Get(context.Background(), id)
+		require.NoError(t, err)
+		return delivery.Status == status
+	}, 2*time.Second, 5*time.Millisecond)
+	return delivery
+}
+
+func TestSignAndVerify(t *testing.T) {
+	payload := []byte(`{"invoice":"INV-1","status":"paid"}`)
+	now := time.Now().Unix()
+	timestamp := strconv.FormatInt(now, 10)
+	signature := Sign(testSecret, now, payload)
+
+	assert.NoError(t, Verify(testSecret, timestamp, signature, payload, time.Minute))
+	assert.ErrorIs(t, Verify([]byte("other"), timestamp, signature, payload, time.Minute), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify(testSecret, timestamp, signature, []byte(`{}`), time.Minute), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify(testSecret, "abc", signature, payload, time.Minute), ErrInvalidSignature)
+
+	old := now - 600
+	assert.ErrorIs(t, Verify(testSecret, strconv.FormatInt(old, 10), Sign(testSecret, old, payload), payload, time.Minute), ErrSignatureExpired)
+}
+
+func TestDispatcherRetriesAndRecordsAttempts(t *testing.T) {
+	payload := []byte(`{"invoice":"INV-1","status":"paid"}`)
+	var calls atomic.Int32
+
+	dummyHandler := func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, payload, body)
+		assert.Equal(t, "payment.paid", r.Header.Get(HeaderEvent))
+		assert.Equal(t, "merchant-1", r.Header.Get("X-Merchant-Id"))
+		assert.NoError(t, Verify(testSecret, r.Header.Get(DefaultTimestampHeader), r.Header.Get(DefaultSignatureHeader), body, time.Minute))
+
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(dummyHandler))
+	defer server.Close()
+
+	mu := &sync.Mutex{}
+	var observed []int
+	dispatcher, err := NewDispatcher(NewMemoryStore(), Config{
+		Secret:       testSecret,
+		Backoff:      httpclient.NewConstantBackoff(5*time.Millisecond, 0),
+		PollInterval: 5 * time.Millisecond,
+		OnAttempt: func(delivery Delivery, attempt Attempt) {
+			mu.Lock()
+			defer mu.Unlock()
+			observed = append(observed, attempt.StatusCode)
+		},
+	})
+	require.NoError(t, err)
+
+	stop := runDispatcher(t, dispatcher)
+	defer stop()
+
+	header := http.Header{}
+	header.Set("X-Merchant-Id", "merchant-1")
+	queued, err := dispatcher.Enqueue(context.Background(), Delivery{URL: server.URL, Event: "payment.paid", Payload: payload, Header: header})
+	require.NoError(t, err)
+	assert.NotEmpty(t, queued.ID)
+
+	delivery := waitStatus(t, dispatcher, queued.ID, StatusSucceeded)
+	require.Len(t, delivery.Attempts, 3)
+	for i, attempt := range delivery.Attempts {
+		assert.Equal(t, i+1, attempt.Number)
+		assert.Greater(t, attempt.Latency, time.Duration(0))
+	}
+	assert.Equal(t, http.StatusServiceUnavailable, delivery.Attempts[0].StatusCode)
+	assert.True(t, delivery.Attempts[2].Succeeded())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []int{503, 503, 200}, observed)
+}
+
+func TestDispatcherFailsAndRedelivers(t *testing.T) {
+	var healthy atomic.Bool
+
+	dummyHandler := func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(dummyHandler))
+	defer server.Close()
+
+	dispatcher, err := NewDispatcher(NewMemoryStore(), Config{
+		SecretFunc: func(delivery *Delivery) ([]byte, error) {
+			return testSecret, nil
+		},
+		Backoff:      httpclient.NewConstantBackoff(time.Millisecond, 0),
+		MaxAttempts:  2,
+		PollInterval: 5 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	stop := runDispatcher(t, dispatcher)
+	defer stop()
+
+	queued, err := dispatcher.Enqueue(context.Background(), Delivery{ID: "dlv-1", URL: server.URL, Event: "payment.paid"})
+	require.NoError(t, err)
+	assert.Equal(t, "dlv-1", queued.ID)
+
+	delivery := waitStatus(t, dispatcher, "dlv-1", StatusFailed)
+	assert.Len(t, delivery.Attempts, 2)
+
+	healthy.Store(true)
+	_, err = dispatcher.Redeliver(context.Background(), "dlv-1")
+	require.NoError(t, err)
+
+	delivery = waitStatus(t, dispatcher, "dlv-1", StatusSucceeded)
+	require.Len(t, delivery.Attempts, 3)
+	assert.Equal(t, http.StatusNoContent, delivery.Attempts[2].StatusCode)
+	assert.Equal(t, 1, delivery.Retry)
+
+	_, err = dispatcher.Redeliver(context.Background(), "unknown")
+	assert.ErrorIs(t, err, ErrDeliveryNotFound)
+}
+
+func TestDispatcherResumesPendingAfterRestart(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	require.NoError(t, err)
+
+	// enqueued by an instance that stopped before sending it
+	first, err := NewDispatcher(store, Config{Secret: testSecret})
+	require.NoError(t, err)
+	queued, err := first.Enqueue(context.Background(), Delivery{URL: server.URL, Event: "payment.paid", Payload: []byte(`{}`)})
+	require.NoError(t, err)
+
+	// a file truncated by a crash must not block the other deliveries
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pending", "broken.json"), []byte(`{"id":`), 0o600))
+
+	var errorCount atomic.Int32
+	reopened, err := NewFileStore(dir)
+	require.NoError(t, err)
+	second, err := NewDispatcher(reopened, Config{
+		Secret:       testSecret,
+		PollInterval: 5 * time.Millisecond,
+		OnError: func(err error) {
+			assert.Contains(t, err.Error(), "broken.json")
+			errorCount.Add(1)
+		},
+	})
+	require.NoError(t, err)
+
+	stop := runDispatcher(t, second)
+	defer stop()
+
+	waitStatus(t, second, queued.ID, StatusSucceeded)
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, int32(1), errorCount.Load(), "a corrupt file is reported once")
+	assert.FileExists(t, filepath.Join(dir, "pending", "broken.json.corrupt"))
+}
+
+func TestNewDispatcherValidation(t *testing.T) {
+	_, err := NewDispatcher(nil, Config{Secret: testSecret})
+	assert.Error(t, err)
+
+	_, err = NewDispatcher(NewMemoryStore(), Config{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultSignatureHeader carries the payload signature, "sha256=<hex>"
+	DefaultSignatureHeader = "X-Webhook-Signature"
+	// DefaultTimestampHeader carries the unix timestamp used in the signature
+	DefaultTimestampHeader = "X-Webhook-Timestamp"
+	// HeaderDeliveryID carries the delivery ID, the same on every attempt so receivers can deduplicate
+	HeaderDeliveryID = "X-Webhook-ID"
+	// HeaderEvent carries the delivery event name
+	HeaderEvent = "X-Webhook-Event"
+
+	signaturePrefix = "sha256="
+)
+
+var (
+	// ErrInvalidSignature is returned by Verify when the signature does not match the payload
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	// ErrSignatureExpired is returned by Verify when the timestamp is outside the tolerance
+	ErrSignatureExpired = errors.New("webhook timestamp outside tolerance")
+)
+
+// Sign returns the signature of payload sent at timestamp. It is the hex encoded
+// HMAC-SHA256 of "<timestamp>.<payload>", prefixed with "sha256=".
+func Sign(secret []byte, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature and timestamp headers received with payload. A tolerance
+// above 0 rejects timestamps further than tolerance from now, which blocks replays.
+func Verify(secret []byte, timestamp, signature string, payload []byte, tolerance time.Duration) error {
+	sentAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.Wrap(ErrInvalidSignature, "invalid timestamp")
+	}
+
+	if tolerance > 0 {
+		age := time.Since(time.Unix(sentAt, 0))
+		if age > tolerance || age < -tolerance {
+			return ErrSignatureExpired
+		}
+	}
+
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return ErrInvalidSignature
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, sentAt, payload))) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
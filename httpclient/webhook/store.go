@@ -0,0 +1,285 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrDeliveryNotFound is returned by a Store when the delivery does not exist
+var ErrDeliveryNotFound = errors.New("webhook delivery not found")
+
+// Store keeps deliveries so pending ones survive a restart. Implementations must be safe
+// for concurrent use. A database backed Store is recommended for large volumes.
+type Store interface {
+	// Save creates or replaces the delivery with the same ID
+	Save(ctx context.Context, delivery *Delivery) error
+	// Get returns the delivery or ErrDeliveryNotFound
+	Get(ctx context.Context, id string) (*Delivery, error)
+	// Due returns up to limit pending deliveries with NextAttemptAt not after now,
+	// oldest NextAttemptAt first. Deliveries that cannot be loaded may be skipped, they are
+	// then reported by an error returned together with the other deliveries.
+	Due(ctx context.Context, now time.Time, limit int) ([]*Delivery, error)
+}
+
+// MemoryStore keeps deliveries in memory, they are lost on restart. Useful for tests.
+type MemoryStore struct {
+	mu         sync.Mutex
+	deliveries map[string]*Delivery
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{deliveries: make(map[string]*Delivery)}
+}
+
+// Save implements Store
+func (s *MemoryStore) Save(_ context.Context, delivery *Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deliveries[delivery.ID] = delivery.clone()
+	return nil
+}
+
+// Get implements Store
+func (s *MemoryStore) Get(_ context.Context, id string) (*Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delivery, ok := s.deliveries[id]
+	if !ok {
+		return nil, ErrDeliveryNotFound
+	}
+
+	return delivery.clone(), nil
+}
+
+// Due implements Store
+func (s *MemoryStore) Due(_ context.Context, now time.Time, limit int) ([]*Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []*Delivery
+	for _, delivery := range s.deliveries {
+		if isDue(delivery, now) {
+			due = append(due, delivery.clone())
+		}
+	}
+
+	return sortDue(due, limit), nil
+}
+
+// FileStore keeps every delivery as a JSON file, so pending deliveries survive a restart of
+// a single instance. Pending deliveries are kept in the "pending" subdirectory and finished
+// ones in "done", so Due only reads the pending files. A file that cannot be decoded is
+// renamed with a ".corrupt" suffix, skipped and reported once by Due.
+type FileStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+const (
+	pendingDir = "pending"
+	doneDir    = "done"
+)
+
+// NewFileStore returns a FileStore writing to dir, the directories are created when missing
+func NewFileStore(dir string) (*FileStore, error) {
+	for _, sub := range []string{pendingDir, doneDir} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o750); err != nil {
+			return nil, errors.Wrap(err, "create webhook store directory")
+		}
+	}
+
+	return &FileStore{dir: dir}, nil
+}
+
+// Save implements Store. The file is synced and replaced atomically. IDs must only contain
+// letters, digits, '-', '_' and '.', and must not start with a '.'.
+func (s *FileStore) Save(_ context.Context, delivery *Delivery) error {
+	if err := validateFileID(delivery.ID); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return errors.Wrap(err, "encode webhook delivery")
+	}
+
+	target, stale := doneDir, pendingDir
+	if delivery.Status == StatusPending {
+		target, stale = pendingDir, doneDir
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tmp, err := os.CreateTemp(filepath.Join(s.dir, target), delivery.ID+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "save webhook delivery")
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "save webhook delivery")
+	}
+	// the data must be on disk before the rename makes it visible
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "save webhook delivery")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "save webhook delivery")
+	}
+
+	if err := os.Rename(tmp.Name(), s.path(target, delivery.ID)); err != nil {
+		return errors.Wrap(err, "save webhook delivery")
+	}
+
+	// a crash before the remove leaves a stale copy, at worst a pending one is sent again
+	if err := os.Remove(s.path(stale, delivery.ID)); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "save webhook delivery")
+	}
+
+	return nil
+}
+
+// Get implements Store
+func (s *FileStore) Get(_ context.Context, id string) (*Delivery, error) {
+	if err := validateFileID(id); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delivery, err := s.read(s.path(pendingDir, id))
+	if errors.Is(err, ErrDeliveryNotFound) {
+		return s.read(s.path(doneDir, id))
+	}
+
+	return delivery, err
+}
+
+// Due implements Store. The deliveries that could be read are returned together with an
+// error listing the files that could not.
+func (s *FileStore) Due(_ context.Context, now time.Time, limit int) ([]*Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir := filepath.Join(s.dir, pendingDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "list webhook deliveries")
+	}
+
+	var due []*Delivery
+	var skipped []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		delivery, err := s.read(path)
+		if errors.Is(err, ErrDeliveryNotFound) {
+			continue
+		}
+		if err != nil {
+			var corrupt *corruptFileError
+			if errors.As(err, &corrupt) {
+				// moved aside so it is reported once instead of on every poll
+				_ = os.Rename(path, path+".corrupt")
+			}
+			skipped = append(skipped, err.Error())
+			continue
+		}
+		if isDue(delivery, now) {
+			due = append(due, delivery)
+		}
+	}
+
+	due = sortDue(due, limit)
+	if len(skipped) > 0 {
+		return due, errors.Errorf("skip %d webhook deliveries: %s", len(skipped), strings.Join(skipped, "; "))
+	}
+
+	return due, nil
+}
+
+func (s *FileStore) path(sub, id string) string {
+	return filepath.Join(s.dir, sub, id+".json")
+}
+
+func (s *FileStore) read(path string) (*Delivery, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "read webhook delivery")
+	}
+
+	var delivery Delivery
+	if err := json.Unmarshal(data, &delivery); err != nil {
+		return nil, &corruptFileError{name: filepath.Base(path), err: err}
+	}
+
+	return &delivery, nil
+}
+
+// corruptFileError is returned for a delivery file that is not valid JSON
+type corruptFileError struct {
+	name string
+	err  error
+}
+
+func (e *corruptFileError) Error() string {
+	return "decode webhook delivery " + e.name + ": " + e.err.Error()
+}
+
+func (e *corruptFileError) Unwrap() error {
+	return e.err
+}
+
+// validateFileID rejects IDs that are not a plain file name, e.g. "../x" or "a/b"
+func validateFileID(id string) error {
+	if id == "" || id[0] == '.' {
+		return errors.Errorf("invalid webhook delivery id %q", id)
+	}
+
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return errors.Errorf("invalid webhook delivery id %q", id)
+		}
+	}
+
+	return nil
+}
+
+func isDue(delivery *Delivery, now time.Time) bool {
+	return delivery.Status == StatusPending && !delivery.NextAttemptAt.After(now)
+}
+
+func sortDue(due []*Delivery, limit int) []*Delivery {
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+	})
+
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+
+	return due
+}
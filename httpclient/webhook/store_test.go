@@ -0,0 +1,99 @@
+package webhook
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStores(t *testing.T) {
+	fileStore, err := NewFileStore(t.TempDir())
+	require.NoError(t, err)
+
+	stores := map[string]Store{
+		"memory": NewMemoryStore(),
+		"file":   fileStore,
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Date(2024, 8, 17, 10, 0, 0, 0, time.UTC)
+
+			deliveries := []*Delivery{
+				{ID: "late", Status: StatusPending, NextAttemptAt: now.Add(-time.Second)},
+				{ID: "early", Status: StatusPending, NextAttemptAt: now.Add(-time.Minute)},
+				{ID: "future", Status: StatusPending, NextAttemptAt: now.Add(time.Minute)},
+				{ID: "done", Status: StatusSucceeded, NextAttemptAt: now.Add(-time.Hour)},
+			}
+			for _, delivery := range deliveries {
+				require.NoError(t, store.Save(ctx, delivery))
+			}
+
+			due, err := store.Due(ctx, now, 10)
+			require.NoError(t, err)
+			require.Len(t, due, 2)
+			assert.Equal(t, "early", due[0].ID)
+			assert.Equal(t, "late", due[1].ID)
+
+			due, err = store.Due(ctx, now, 1)
+			require.NoError(t, err)
+			require.Len(t, due, 1)
+
+			delivery, err := store.Get(ctx, "early")
+			require.NoError(t, err)
+			delivery.Attempts = append(delivery.Attempts, Attempt{Number: 1, StatusCode: 500, Latency: time.Millisecond})
+			delivery.Status = StatusFailed
+			require.NoError(t, store.Save(ctx, delivery))
+
+			saved, err := store.Get(ctx, "early")
+			require.NoError(t, err)
+			assert.Equal(t, StatusFailed, saved.Status)
+			assert.Equal(t, delivery.Attempts, saved.Attempts)
+
+			_, err = store.Get(ctx, "missing")
+			assert.ErrorIs(t, err, ErrDeliveryNotFound)
+		})
+	}
+}
+
+func TestFileStoreLayout(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	require.NoError(t, err)
+
+	delivery := &Delivery{ID: "inv-1", Status: StatusPending}
+	require.NoError(t, store.Save(ctx, delivery))
+	assert.FileExists(t, filepath.Join(dir, "pending", "inv-1.json"))
+
+	// a finished delivery moves out of the directory read by Due
+	delivery.Status = StatusSucceeded
+	require.NoError(t, store.Save(ctx, delivery))
+	assert.FileExists(t, filepath.Join(dir, "done", "inv-1.json"))
+	assert.NoFileExists(t, filepath.Join(dir, "pending", "inv-1.json"))
+
+	saved, err := store.Get(ctx, "inv-1")
+	require.NoError(t, err)
+	assert.Equal(t, StatusSucceeded, saved.Status)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pending", "broken.json"), []byte("{"), 0o600))
+	due, err := store.Due(ctx, time.Now(), 10)
+	require.Error(t, err)
+	assert.Empty(t, due)
+
+	due, err = store.Due(ctx, time.Now(), 10)
+	require.NoError(t, err, "the corrupt file is only reported once")
+	assert.Empty(t, due)
+
+	for _, id := range []string{"", "../escape", "a/b", `a\b`, ".hidden"} {
+		assert.Error(t, store.Save(ctx, &Delivery{ID: id, Status: StatusPending}), id)
+		_, err := store.Get(ctx, id)
+		assert.Error(t, err, id)
+	}
+}